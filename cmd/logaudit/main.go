@@ -0,0 +1,57 @@
+// logaudit verifies the hash chain of the files written by the audit output of package log.
+//
+//	logaudit [-key-file file] audit.log...
+//
+// It prints the number of valid lines of every file, or the first broken link,
+// and exits with 1 if any file is broken, or 2 for other errors.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/byte-power/go-utility/log"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file of the HMAC key used by the audit output")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-key-file file] audit.log...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var key []byte
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		key = bytes.TrimSpace(data)
+	}
+
+	code := 0
+	for _, location := range flag.Args() {
+		count, err := log.VerifyAuditFile(location, key)
+		var auditErr *log.AuditError
+		switch {
+		case err == nil:
+			fmt.Printf("%s: ok, %d lines\n", location, count)
+		case errors.As(err, &auditErr):
+			fmt.Printf("%s: broken at line %d: %s\n", location, auditErr.Line, auditErr.Reason)
+			code = max(code, 1)
+		default:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", location, err)
+			code = 2
+		}
+	}
+	os.Exit(code)
+}
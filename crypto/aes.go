@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 
 var (
 	ErrCipherTextLengthIncorrect = errors.New("Cipher text is not a multiple of the block size ")
+	ErrCipherTextTooShort        = errors.New("Cipher text is too short")
 )
 
 type Coder interface {
@@ -134,3 +136,54 @@ func (coder aesCBCCoder) Decrypt(src []byte) ([]byte, error) {
 	// 解填充
 	return pkcs7strip(encryptData)
 }
+
+// AEADCoder is a Coder authenticating additional data with the plain text,
+// the cipher text is decrypted only with the same additional data, which is not included in it.
+type AEADCoder interface {
+	Coder
+	EncryptWithData(src, additionalData []byte) ([]byte, error)
+	DecryptWithData(src, additionalData []byte) ([]byte, error)
+}
+
+// NewAESCoderWithGCM returns an authenticated Coder implementing AEADCoder,
+// a random nonce is generated for every encryption and prepended to the cipher text.
+func NewAESCoderWithGCM(key []byte) (Coder, error) {
+	c, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	aead, e := cipher.NewGCM(c)
+	if e != nil {
+		return nil, e
+	}
+	return aesGCMCoder{aead: aead}, nil
+}
+
+type aesGCMCoder struct {
+	aead cipher.AEAD
+}
+
+func (coder aesGCMCoder) Encrypt(src []byte) ([]byte, error) {
+	return coder.EncryptWithData(src, nil)
+}
+
+func (coder aesGCMCoder) Decrypt(src []byte) ([]byte, error) {
+	return coder.DecryptWithData(src, nil)
+}
+
+func (coder aesGCMCoder) EncryptWithData(src, additionalData []byte) ([]byte, error) {
+	nonceSize := coder.aead.NonceSize()
+	dst := make([]byte, nonceSize, nonceSize+len(src)+coder.aead.Overhead())
+	if _, err := rand.Read(dst); err != nil {
+		return nil, err
+	}
+	return coder.aead.Seal(dst, dst, src, additionalData), nil
+}
+
+func (coder aesGCMCoder) DecryptWithData(src, additionalData []byte) ([]byte, error) {
+	nonceSize := coder.aead.NonceSize()
+	if len(src) < nonceSize+coder.aead.Overhead() {
+		return nil, ErrCipherTextTooShort
+	}
+	return coder.aead.Open(nil, src[:nonceSize], src[nonceSize:], additionalData)
+}
@@ -48,3 +48,54 @@ func TestNewAESCoderWithCBC(t *testing.T) {
 		t.Log("success")
 	}
 }
+
+func TestNewAESCoderWithGCM(t *testing.T) {
+	key := []byte("1234567890abcdef")
+	plain := []byte("Hello")
+
+	gcm, err := NewAESCoderWithGCM(key)
+	if err != nil {
+		t.Fatal("fail", err)
+	}
+	encryptBytes, err := gcm.Encrypt(plain)
+	if err != nil {
+		t.Fatal("fail", err)
+	}
+	another, _ := gcm.Encrypt(plain)
+	if string(encryptBytes) == string(another) {
+		t.Fatal("nonce is reused")
+	}
+	decryptBytes, err := gcm.Decrypt(encryptBytes)
+	if err != nil {
+		t.Fatal("fail", err)
+	}
+	if string(decryptBytes) != string(plain) {
+		t.Fatal("decrypted text mismatched")
+	}
+
+	encryptBytes[len(encryptBytes)-1] ^= 1
+	if _, err := gcm.Decrypt(encryptBytes); err == nil {
+		t.Fatal("modified cipher text is decrypted")
+	}
+	if _, err := gcm.Decrypt(encryptBytes[:4]); err != ErrCipherTextTooShort {
+		t.Fatal("short cipher text is decrypted", err)
+	}
+
+	aead, ok := gcm.(AEADCoder)
+	if !ok {
+		t.Fatal("gcm is not an AEADCoder")
+	}
+	encryptBytes, err = aead.EncryptWithData(plain, []byte("line 1"))
+	if err != nil {
+		t.Fatal("fail", err)
+	}
+	if decryptBytes, err = aead.DecryptWithData(encryptBytes, []byte("line 1")); err != nil || string(decryptBytes) != string(plain) {
+		t.Fatal("decrypted text mismatched", err)
+	}
+	if _, err := aead.DecryptWithData(encryptBytes, []byte("line 2")); err == nil {
+		t.Fatal("cipher text is decrypted with other additional data")
+	}
+	if _, err := gcm.Decrypt(encryptBytes); err == nil {
+		t.Fatal("cipher text is decrypted without additional data")
+	}
+}
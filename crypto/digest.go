@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -30,3 +31,10 @@ func SHA512(src []byte) []byte {
 	d.Write(src)
 	return d.Sum(nil)
 }
+
+// HMACSHA256 returns the HMAC of src with SHA-256 keyed by key.
+func HMACSHA256(key, src []byte) []byte {
+	d := hmac.New(sha256.New, key)
+	d.Write(src)
+	return d.Sum(nil)
+}
@@ -38,6 +38,12 @@ func TestDigest(t *testing.T) {
 	t.Log("success")
 
 }
+
+func TestHMACSHA256(t *testing.T) {
+	// RFC 4231 test case 2
+	mac := HMACSHA256([]byte("Jefe"), []byte("what do ya want for nothing?"))
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", fmt.Sprintf("%x", mac))
+}
 func BenchmarkMD5(b *testing.B) {
 	plain := []byte("Hello")
 	b.ResetTimer()
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what AsyncOutput does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record being logged.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued record to make room.
+	OverflowDropOldest
+)
+
+const defaultAsyncQueueSize = 1024
+
+type AsyncConfig struct {
+	// QueueSize is the capacity of the queue, default is 1024.
+	QueueSize int
+	Overflow  OverflowPolicy
+}
+
+type asyncRecord struct {
+	// pc is the caller captured when the record is queued
+	pc      uintptr
+	level   Level
+	subject string
+	pairs   []LogPair
+	// flushed is set for the marker record of Sync
+	flushed chan struct{}
+}
+
+// AsyncOutput writes records into the wrapped Output on its own goroutine.
+type AsyncOutput struct {
+	outputWrapper
+	overflow OverflowPolicy
+	queue    chan asyncRecord
+	dropped  atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewAsyncOutput wraps output with a bounded queue,
+// close it to drain the queue and close the wrapped output.
+func NewAsyncOutput(output Output, cfg AsyncConfig) *AsyncOutput {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultAsyncQueueSize
+	}
+	o := &AsyncOutput{
+		outputWrapper: outputWrapper{output},
+		overflow:      cfg.Overflow,
+		queue:         make(chan asyncRecord, cfg.QueueSize),
+		done:          make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+func (o *AsyncOutput) run() {
+	defer close(o.done)
+	for record := range o.queue {
+		if record.flushed != nil {
+			close(record.flushed)
+			continue
+		}
+		logToOutput(o.Output, record.pc, record.level, record.subject, record.pairs)
+	}
+}
+
+func (o *AsyncOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o *AsyncOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	record := asyncRecord{pc: pc, level: l, subject: subject, pairs: append([]LogPair(nil), pairs...)}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.closed {
+		o.dropped.Add(1)
+		return
+	}
+	switch o.overflow {
+	case OverflowDropNewest:
+		select {
+		case o.queue <- record:
+		default:
+			o.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case o.queue <- record:
+				return
+			default:
+			}
+			select {
+			case oldest := <-o.queue:
+				if oldest.flushed != nil {
+					close(oldest.flushed)
+				} else {
+					o.dropped.Add(1)
+				}
+			default:
+			}
+		}
+	default:
+		o.queue <- record
+	}
+}
+
+// Dropped returns how many records have been dropped by the overflow policy or after closed.
+func (o *AsyncOutput) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// Sync waits for the records queued before it are written, then syncs the wrapped output.
+func (o *AsyncOutput) Sync() error {
+	o.mu.RLock()
+	if o.closed {
+		o.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	o.queue <- asyncRecord{flushed: flushed}
+	o.mu.RUnlock()
+	<-flushed
+	return o.outputWrapper.Sync()
+}
+
+// Close drains the queue, then syncs and closes the wrapped output.
+func (o *AsyncOutput) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	close(o.queue)
+	o.mu.Unlock()
+	<-o.done
+	return errors.Join(o.outputWrapper.Sync(), o.outputWrapper.Close())
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gateOutput blocks writing until the gate is opened.
+type gateOutput struct {
+	*Recorder
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func newGateOutput() *gateOutput {
+	return &gateOutput{Recorder: NewRecorder("", LevelDebug), entered: make(chan struct{}, 1), gate: make(chan struct{})}
+}
+
+func (o *gateOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	select {
+	case o.entered <- struct{}{}:
+	default:
+	}
+	<-o.gate
+	o.Recorder.LogModuleAndPairs(l, subject, pairs)
+}
+
+func TestAsyncOutputOverflow(t *testing.T) {
+	cases := []struct {
+		overflow OverflowPolicy
+		subjects []string
+	}{
+		{OverflowDropNewest, []string{"0", "1", "2"}},
+		{OverflowDropOldest, []string{"0", "3", "4"}},
+	}
+	for _, it := range cases {
+		inner := newGateOutput()
+		o := NewAsyncOutput(inner, AsyncConfig{QueueSize: 2, Overflow: it.overflow})
+		l := NewLogger(o)
+		l.Info("0")
+		// the worker is blocked by "0", so the queue is full after "2"
+		<-inner.entered
+		for _, subject := range []string{"1", "2", "3", "4"} {
+			l.Info(subject)
+		}
+		assert.Equal(t, uint64(2), o.Dropped())
+		close(inner.gate)
+		assert.Nil(t, o.Close())
+		assert.Equal(t, it.subjects, inner.Subjects())
+
+		l.Info("5")
+		assert.Equal(t, uint64(3), o.Dropped())
+		assert.Nil(t, o.Close())
+	}
+}
+
+func TestAsyncOutputBlockAndSync(t *testing.T) {
+	inner := NewRecorder("", LevelDebug)
+	o := NewAsyncOutput(inner, AsyncConfig{QueueSize: 1})
+	l := NewLogger(o)
+	pairs := []LogPair{Any("a", 1)}
+	for i := 0; i < 100; i++ {
+		l.Info("a", pairs...)
+	}
+	pairs[0] = Any("a", 2)
+	assert.Nil(t, l.Sync())
+	assert.Equal(t, 100, len(inner.Subjects()))
+	last, _ := inner.Last()
+	assert.Equal(t, []LogPair{Any("a", 1)}, last.Pairs)
+	assert.Equal(t, uint64(0), o.Dropped())
+	assert.Nil(t, l.Close())
+}
+
+func TestAsyncOutputCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	output := NewAsyncOutput(NewTeeOutput("tee", MakeWriterOutput("", MakeLocalFormat(MessageFormatLogfmt), LevelInfo, buf)), AsyncConfig{})
+	l := NewLogger(output)
+	l.Info("queued")
+	assert.Nil(t, l.Close())
+	// the caller is captured when the record is queued
+	assert.Regexp(t, regexp.MustCompile(`caller=log/async_test\.go:\d+ msg=queued`), buf.String())
+}
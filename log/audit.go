@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/byte-power/go-utility/crypto"
+)
+
+const (
+	auditHashKey = "audit_hash"
+	auditHashLen = 32
+	// the lines end with it plus the hex hash and "}"
+	auditHashPrefix = `,"` + auditHashKey + `":"`
+)
+
+type AuditConfig struct {
+	// KeyFile is the file of the HMAC key, SHA-256 is used if it's empty.
+	KeyFile string `yaml:"key_file" json:"key_file"`
+}
+
+func (cfg AuditConfig) key() ([]byte, error) {
+	if cfg.KeyFile == "" {
+		return nil, nil
+	}
+	key, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(key), nil
+}
+
+// MakeAuditOutput makes an Output appending tamper-evident records to location.
+// Every line is a JSON record ending with audit_hash, which is the hex SHA-256 of
+// the previous hash and the line without audit_hash, or HMAC-SHA256 keyed by key if key is not empty.
+// The hash before the first line is all zeros, and the chain continues if location exists.
+// The file is not rotated, and it should be verified by VerifyAuditLog.
+func MakeAuditOutput(name string, fmt LocalFormat, level Level, location string, key []byte) (Output, error) {
+	w, err := newAuditWriter(location, key)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Format = MessageFormatJSON
+	output := newZapLogger(name, fmt, level, w)
+	output.closer = w
+	return output, nil
+}
+
+func auditHash(key, prev, content []byte) []byte {
+	src := make([]byte, 0, len(prev)+len(content))
+	src = append(append(src, prev...), content...)
+	if len(key) > 0 {
+		return crypto.HMACSHA256(key, src)
+	}
+	return crypto.SHA256(src)
+}
+
+// splitAuditLine returns the content and the hash of line.
+func splitAuditLine(line []byte) (content, hash []byte, err error) {
+	i := bytes.LastIndex(line, []byte(auditHashPrefix))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, nil, errors.New("audit_hash is missing")
+	}
+	hash, err = hex.DecodeString(string(line[i+len(auditHashPrefix) : len(line)-2]))
+	if err != nil || len(hash) != auditHashLen {
+		return nil, nil, errors.New("audit_hash is malformed")
+	}
+	content = append(line[:i:i], '}')
+	return content, hash, nil
+}
+
+// -------------------------------
+
+type auditWriter struct {
+	key []byte
+
+	mu   sync.Mutex
+	file *os.File
+	prev []byte
+}
+
+func newAuditWriter(location string, key []byte) (*auditWriter, error) {
+	file, err := os.OpenFile(location, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := &auditWriter{key: key, file: file, prev: make([]byte, auditHashLen)}
+	line, err := lastLine(file)
+	if err == nil && len(line) > 0 {
+		var hash []byte
+		if _, hash, err = splitAuditLine(line); err == nil {
+			w.prev = hash
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("log: audit file %s: %w", location, err)
+	}
+	return w, nil
+}
+
+// Write appends one encoded record with its hash.
+func (w *auditWriter) Write(p []byte) (int, error) {
+	content := bytes.TrimRight(p, "\n")
+	if !bytes.HasSuffix(content, []byte("}")) {
+		return 0, errors.New("log: audit record is not a JSON object")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	hash := auditHash(w.key, w.prev, content)
+	line := make([]byte, 0, len(content)+len(auditHashPrefix)+auditHashLen*2+3)
+	line = append(line, content[:len(content)-1]...)
+	line = append(line, auditHashPrefix...)
+	line = line[:len(line)+hex.EncodedLen(len(hash))]
+	hex.Encode(line[len(line)-hex.EncodedLen(len(hash)):], hash)
+	line = append(line, "\"}\n"...)
+	if _, err := w.file.Write(line); err != nil {
+		return 0, err
+	}
+	w.prev = hash
+	return len(p), nil
+}
+
+func (w *auditWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+func (w *auditWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// lastLine reads the last non-empty line of f backwards.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	var line []byte
+	chunk := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		n := int64(len(chunk))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(chunk[:n], offset); err != nil {
+			return nil, err
+		}
+		line = append(append([]byte(nil), chunk[:n]...), line...)
+		trimmed := bytes.TrimRight(line, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(line, "\n"), nil
+}
+
+// -------------------------------
+
+// AuditError is the first broken link found by VerifyAuditLog.
+type AuditError struct {
+	// Line is 1-based
+	Line   int
+	Reason string
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("log: audit line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyAuditLog walks the lines written by the audit output and checks the chain of hashes,
+// key should be the same as the one of MakeAuditOutput.
+// It returns the number of valid lines, and an *AuditError for the first broken link.
+// The removal of the last lines can't be detected, keep the last hash elsewhere if it matters.
+func VerifyAuditLog(r io.Reader, key []byte) (int, error) {
+	reader := bufio.NewReader(r)
+	prev := make([]byte, auditHashLen)
+	count := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\n")
+			content, hash, splitErr := splitAuditLine(line)
+			if splitErr != nil {
+				return count, &AuditError{Line: count + 1, Reason: splitErr.Error()}
+			}
+			if !bytes.Equal(hash, auditHash(key, prev, content)) {
+				return count, &AuditError{Line: count + 1, Reason: "audit_hash mismatched, the line or the ones before are modified"}
+			}
+			prev = hash
+			count++
+		}
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// VerifyAuditFile is VerifyAuditLog of the file at location.
+func VerifyAuditFile(location string, key []byte) (int, error) {
+	file, err := os.Open(location)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return VerifyAuditLog(file, key)
+}
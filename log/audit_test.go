@@ -0,0 +1,112 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeAuditLog(t *testing.T, location string, key []byte, subjects ...string) {
+	output, err := MakeAuditOutput("audit", MakeLocalFormat(MessageFormatText), LevelInfo, location, key)
+	assert.Nil(t, err)
+	l := NewLogger(output)
+	for _, it := range subjects {
+		l.Info(it, String("user", "tom"))
+	}
+	assert.Nil(t, l.Close())
+}
+
+func TestAuditOutput(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "audit.log")
+	writeAuditLog(t, location, nil, "login", "update")
+	// the chain continues after reopened
+	writeAuditLog(t, location, nil, "logout")
+
+	data, err := os.ReadFile(location)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 3, len(lines))
+	record := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[2]), &record))
+	assert.Equal(t, "logout", record["msg"])
+	assert.Equal(t, 64, len(record[auditHashKey].(string)))
+
+	count, err := VerifyAuditFile(location, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	// modified content
+	tampered := strings.Replace(string(data), `"user":"tom"`, `"user":"bob"`, 1)
+	count, err = VerifyAuditLog(strings.NewReader(tampered), nil)
+	var auditErr *AuditError
+	assert.True(t, errors.As(err, &auditErr))
+	assert.Equal(t, 1, auditErr.Line)
+	assert.Equal(t, 0, count)
+
+	// removed line
+	count, err = VerifyAuditLog(strings.NewReader(lines[0]+"\n"+lines[2]+"\n"), nil)
+	assert.EqualError(t, err, "log: audit line 2: audit_hash mismatched, the line or the ones before are modified")
+	assert.Equal(t, 1, count)
+
+	// line without hash
+	_, err = VerifyAuditLog(strings.NewReader(lines[0]+"\n"+`{"msg":"forged"}`+"\n"), nil)
+	assert.EqualError(t, err, "log: audit line 2: audit_hash is missing")
+}
+
+func TestAuditOutputHMAC(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+	writeAuditLog(t, location, key, "login", "logout")
+
+	count, err := VerifyAuditFile(location, key)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	_, err = VerifyAuditFile(location, []byte("other"))
+	assert.NotNil(t, err)
+	_, err = VerifyAuditFile(location, nil)
+	assert.NotNil(t, err)
+}
+
+func TestAuditOutputBrokenFile(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "audit.log")
+	assert.Nil(t, os.WriteFile(location, []byte("not audit\n"), 0o644))
+	_, err := MakeAuditOutput("audit", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, nil)
+	assert.NotNil(t, err)
+
+	// a long last line is read by chunks
+	long := bytes.Repeat([]byte("x"), 10000)
+	f, err := os.CreateTemp(t.TempDir(), "lines")
+	assert.Nil(t, err)
+	defer f.Close()
+	f.Write([]byte("first\n"))
+	f.Write(long)
+	f.Write([]byte("\n\n"))
+	line, err := lastLine(f)
+	assert.Nil(t, err)
+	assert.Equal(t, long, line)
+}
+
+func TestAuditOutputConfig(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	assert.Nil(t, os.WriteFile(keyFile, []byte("secret\n"), 0o600))
+	location := filepath.Join(dir, "audit.log")
+	logger, err := NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: OutputTypeAudit, Location: location, Audit: AuditConfig{KeyFile: keyFile}},
+	}})
+	assert.Nil(t, err)
+	logger.Info("login")
+	assert.Nil(t, logger.Close())
+	count, err := VerifyAuditFile(location, []byte("secret"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{{Type: OutputTypeAudit}}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultCEFVendor       = "byte-power"
+	defaultCEFVersion      = "1.0"
+	defaultCEFSignatureKey = "signature_id"
+)
+
+var cefBufferPool = buffer.NewPool()
+
+// CEFHeader is the header of ArcSight CEF used by MessageFormatCEF.
+type CEFHeader struct {
+	// Vendor is the Device Vendor, default is byte-power.
+	Vendor string `yaml:"vendor" json:"vendor"`
+	// Product is the Device Product, default is the name of the executable.
+	Product string `yaml:"product" json:"product"`
+	// Version is the Device Version, default is 1.0.
+	Version string `yaml:"version" json:"version"`
+	// SignatureKey is the key of the pair used as Signature ID, default is signature_id.
+	// The subject is used if the pair is absent.
+	SignatureKey string `yaml:"signature_key" json:"signature_key"`
+}
+
+func (h CEFHeader) withDefaults() CEFHeader {
+	if h.Vendor == "" {
+		h.Vendor = defaultCEFVendor
+	}
+	if h.Product == "" {
+		h.Product = filepath.Base(os.Args[0])
+	}
+	if h.Version == "" {
+		h.Version = defaultCEFVersion
+	}
+	if h.SignatureKey == "" {
+		h.SignatureKey = defaultCEFSignatureKey
+	}
+	return h
+}
+
+// cefEncoder renders a record as CEF:
+//
+//	CEF:0|Vendor|Product|Version|Signature ID|subject|Severity|rt=1700000000000 deviceFacility=app key=value
+//
+// The subject is the Name, the level is mapped to Severity,
+// and the time is rt in milliseconds, the keys of LocalFormat are not used except CallerKey.
+type cefEncoder struct {
+	*fieldEncoder
+	cfg    zapcore.EncoderConfig
+	header CEFHeader
+}
+
+func newCEFEncoder(cfg zapcore.EncoderConfig, header CEFHeader) zapcore.Encoder {
+	return cefEncoder{fieldEncoder: &fieldEncoder{}, cfg: cfg, header: header.withDefaults()}
+}
+
+func (e cefEncoder) Clone() zapcore.Encoder {
+	e.fieldEncoder = e.fieldEncoder.clone()
+	return e
+}
+
+func (e cefEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.fieldEncoder.clone()
+	for _, it := range fields {
+		it.AddTo(enc)
+	}
+	signature := ent.Message
+	extensions := enc.fields[:0:0]
+	for _, it := range enc.fields {
+		if it.key == e.header.SignatureKey {
+			signature = it.value
+			continue
+		}
+		extensions = append(extensions, it)
+	}
+
+	buf := cefBufferPool.Get()
+	buf.AppendString("CEF:0")
+	for _, it := range []string{e.header.Vendor, e.header.Product, e.header.Version, signature, ent.Message} {
+		buf.AppendByte('|')
+		buf.AppendString(cefEscapeHeader(it))
+	}
+	buf.AppendByte('|')
+	buf.AppendInt(int64(cefSeverity(ent.Level)))
+	buf.AppendByte('|')
+
+	ext := &cefExtension{buf: buf}
+	if e.cfg.TimeKey != "" {
+		ext.append("rt", strconv.FormatInt(ent.Time.UnixMilli(), 10))
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		ext.append("deviceFacility", ent.LoggerName)
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		ext.append(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	for _, it := range extensions {
+		ext.append(it.key, it.value)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// cefSeverity maps level to 0-10.
+func cefSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 1
+	case zapcore.InfoLevel:
+		return 3
+	case zapcore.WarnLevel:
+		return 6
+	case zapcore.ErrorLevel:
+		return 8
+	default:
+		return 10
+	}
+}
+
+type cefExtension struct {
+	buf   *buffer.Buffer
+	count int
+}
+
+func (e *cefExtension) append(key, value string) {
+	if e.count > 0 {
+		e.buf.AppendByte(' ')
+	}
+	e.count++
+	e.buf.AppendString(cefKey(key))
+	e.buf.AppendByte('=')
+	e.buf.AppendString(cefEscapeExtension(value))
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefEscapeHeader(s string) string { return cefHeaderEscaper.Replace(s) }
+
+func cefEscapeExtension(s string) string { return cefExtensionEscaper.Replace(s) }
+
+// cefKey replaces the characters other than letters, digits, '_' and '.' with '_'.
+func cefKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, key)
+}
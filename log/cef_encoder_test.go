@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCEFEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	format := MakeLocalFormat(MessageFormatCEF)
+	format.CEF = CEFHeader{Vendor: "ACME|Corp", Product: "portal", Version: "2.1"}
+	l := NewLogger(newZapLogger("audit", format, LevelDebug, newZapWriter(buf)))
+	l.Warn("user login", String("signature_id", "auth:100"), String("suser", "tom"),
+		String("query", `a=1\b`), String("multi", "a\nb"), String("bad key", "x"))
+
+	assert.Regexp(t, regexp.MustCompile(`^CEF:0\|ACME\\\|Corp\|portal\|2\.1\|auth:100\|user login\|6\|`+
+		`rt=\d{13} deviceFacility=audit caller=log/cef_encoder_test\.go:\d+ suser=tom query=a\\=1\\\\b multi=a\\nb bad_key=x\n$`), buf.String())
+}
+
+func TestCEFEncoderDefaults(t *testing.T) {
+	format := MakeLocalFormat(MessageFormatCEF)
+	format.CallerKey = ""
+	format.CEF.Product = "app"
+	enc := makeZapEncoder(format)
+	at := time.UnixMilli(1700000000123)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: at, Message: "a|b\nc"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `CEF:0|byte-power|app|1.0|a\|b c|a\|b c|8|rt=1700000000123`+"\n", buf.String())
+
+	cfg := OutputConfig{Type: "console", Format: "cef", CEF: CEFHeader{Vendor: "v"}}
+	f, err := cfg.localFormat()
+	assert.Nil(t, err)
+	assert.Equal(t, MessageFormatCEF, f.Format)
+	assert.Equal(t, "v", f.CEF.Vendor)
+	assert.Equal(t, MessageFormatLogfmt, MakeMessageFormat("LOGFMT"))
+}
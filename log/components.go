@@ -1,8 +1,11 @@
 package log
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type Level int
@@ -64,6 +67,58 @@ func MakeLevelWithName(name string) Level {
 	}
 }
 
+// ParseLevel is the strict version of MakeLevelWithName,
+// it returns an error instead of LevelDebug for an unknown name.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	default:
+		return LevelDebug, fmt.Errorf("log: unknown level %q", name)
+	}
+}
+
+// LevelVar is a Level variable which is safe to be read and changed concurrently.
+type LevelVar struct {
+	v atomic.Int32
+}
+
+func NewLevelVar(level Level) *LevelVar {
+	v := &LevelVar{}
+	v.Set(level)
+	return v
+}
+
+// Level returns LevelDebug for nil LevelVar.
+func (v *LevelVar) Level() Level {
+	if v == nil {
+		return LevelDebug
+	}
+	return Level(v.v.Load())
+}
+
+func (v *LevelVar) Set(level Level) {
+	v.v.Store(int32(level))
+}
+
+// LevelSetter is implemented by the Output whose level can be changed at runtime.
+type LevelSetter interface {
+	SetLevel(level Level)
+}
+
+// NamedOutput is implemented by the Output with a name.
+type NamedOutput interface {
+	Name() string
+}
+
 // -------------------------------
 
 type MessageFormat string
@@ -71,6 +126,13 @@ type MessageFormat string
 const (
 	MessageFormatJSON MessageFormat = "json"
 	MessageFormatText MessageFormat = "text"
+	// MessageFormatDev is human-friendly for local development,
+	// the levels are colored by MakeConsoleOutput if the stream is a terminal.
+	MessageFormatDev MessageFormat = "dev"
+	// MessageFormatLogfmt renders the records as logfmt, key=value separated by spaces.
+	MessageFormatLogfmt MessageFormat = "logfmt"
+	// MessageFormatCEF renders the records as ArcSight CEF, the header is LocalFormat.CEF.
+	MessageFormatCEF MessageFormat = "cef"
 )
 
 // MakeMessageFormat would product MessageFormat with raw string.
@@ -79,13 +141,33 @@ func MakeMessageFormat(raw string) MessageFormat {
 	switch strings.ToLower(raw) {
 	case string(MessageFormatText):
 		return MessageFormatText
+	case string(MessageFormatDev):
+		return MessageFormatDev
+	case string(MessageFormatLogfmt):
+		return MessageFormatLogfmt
+	case string(MessageFormatCEF):
+		return MessageFormatCEF
 	default:
 		return MessageFormatJSON
 	}
 }
 
-func (f MessageFormat) isJSON() bool {
-	return f == MessageFormatJSON
+// ParseMessageFormat is the strict version of MakeMessageFormat.
+func ParseMessageFormat(raw string) (MessageFormat, error) {
+	switch strings.ToLower(raw) {
+	case string(MessageFormatJSON):
+		return MessageFormatJSON, nil
+	case string(MessageFormatText):
+		return MessageFormatText, nil
+	case string(MessageFormatDev):
+		return MessageFormatDev, nil
+	case string(MessageFormatLogfmt):
+		return MessageFormatLogfmt, nil
+	case string(MessageFormatCEF):
+		return MessageFormatCEF, nil
+	default:
+		return MessageFormatJSON, fmt.Errorf("log: unknown message format %q", raw)
+	}
 }
 
 // -------------------------------
@@ -115,6 +197,16 @@ func MakeTimeFormat(raw string) TimeFormat {
 	}
 }
 
+// ParseTimeFormat is the strict version of MakeTimeFormat.
+func ParseTimeFormat(raw string) (TimeFormat, error) {
+	switch f := TimeFormat(strings.ToLower(raw)); f {
+	case TimeFormatRFC3339, TimeFormatISO8601, TimeFormatSeconds, TimeFormatMillis, TimeFormatNanos:
+		return f, nil
+	default:
+		return TimeFormatRFC3339, fmt.Errorf("log: unknown time format %q", raw)
+	}
+}
+
 // -------------------------------
 
 type LocalFormat struct {
@@ -127,6 +219,8 @@ type LocalFormat struct {
 	CallerKey  string
 	// 时间格式
 	TimeFormat TimeFormat // 默认为TimeFormatRFC3339
+	// CEF is used by MessageFormatCEF
+	CEF CEFHeader
 }
 
 func MakeLocalFormat(msg MessageFormat) LocalFormat {
@@ -159,6 +253,16 @@ func MakeConsoleStream(raw string) ConsoleStream {
 	}
 }
 
+// ParseConsoleStream is the strict version of MakeConsoleStream.
+func ParseConsoleStream(raw string) (ConsoleStream, error) {
+	switch s := ConsoleStream(strings.ToLower(raw)); s {
+	case ConsoleStreamStdout, ConsoleStreamStderr:
+		return s, nil
+	default:
+		return ConsoleStreamStdout, fmt.Errorf("log: unknown console stream %q", raw)
+	}
+}
+
 func (s ConsoleStream) stream() *os.File {
 	switch s {
 	case ConsoleStreamStderr:
@@ -173,17 +277,38 @@ func (s ConsoleStream) stream() *os.File {
 // Rotation stores configs for the log rotation.
 // See more in https://github.com/natefinch/lumberjack/tree/v2.0
 type FileRotation struct {
-	MaxSize    int
-	Compress   bool
-	MaxAge     int
-	MaxBackups int
-	LocalTime  bool
+	MaxSize    int  `yaml:"max_size" json:"max_size"`
+	Compress   bool `yaml:"compress" json:"compress"`
+	MaxAge     int  `yaml:"max_age" json:"max_age"`
+	MaxBackups int  `yaml:"max_backups" json:"max_backups"`
+	LocalTime  bool `yaml:"local_time" json:"local_time"`
 	// RotateOnTime enables log rotation based on time.
-	RotateOnTime bool
+	RotateOnTime bool `yaml:"rotate_on_time" json:"rotate_on_time"`
 	// RotatePeriod is the period for log rotation.
 	// Supports daily(d), hourly(h), minute(m) and second(s).
-	RotatePeriod string
+	RotatePeriod string `yaml:"rotate_period" json:"rotate_period"`
 	// RotateAfter sets a value for time based rotation.
 	// Log file rotates every RotateAfter * RotatePeriod.
-	RotateAfter int
+	RotateAfter int `yaml:"rotate_after" json:"rotate_after"`
+	// Location is where the rotation period is aligned,
+	// default is time.Local if LocalTime is set, otherwise time.UTC.
+	Location *time.Location `yaml:"-" json:"-"`
+	// AfterRotate hooks are called after every time based rotation.
+	AfterRotate []RotateHook `yaml:"-" json:"-"`
+}
+
+func (r FileRotation) validate() error {
+	if r.MaxSize < 0 || r.MaxAge < 0 || r.MaxBackups < 0 {
+		return fmt.Errorf("log: negative rotation limits")
+	}
+	if !r.RotateOnTime {
+		return nil
+	}
+	if _, ok := rotationPeriodUnit(r.RotatePeriod); !ok {
+		return fmt.Errorf("log: unknown rotate period %q", r.RotatePeriod)
+	}
+	if r.RotateAfter <= 0 {
+		return fmt.Errorf("log: rotate_after should be positive, got %d", r.RotateAfter)
+	}
+	return nil
 }
@@ -0,0 +1,245 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OutputTypeConsole  = "console"
+	OutputTypeFile     = "file"
+	OutputTypeSyslog   = "syslog"
+	OutputTypeJournald = "journald"
+	OutputTypeShip     = "ship"
+	OutputTypeAudit    = "audit"
+)
+
+// Config describes a Logger and its outputs,
+// it can be decoded from YAML or JSON.
+type Config struct {
+	// Level of the Logger itself, default is debug.
+	Level string `yaml:"level" json:"level"`
+	// DuplicateKeys is the DuplicatePolicy: collect(default), first, last or suffix.
+	DuplicateKeys string `yaml:"duplicate_keys" json:"duplicate_keys"`
+	// Redact enables the redaction of sensitive values for all outputs.
+	Redact *RedactConfig `yaml:"redact" json:"redact"`
+	// ErrorStack logs the stack of the call site for error and fatal records.
+	ErrorStack bool           `yaml:"error_stack" json:"error_stack"`
+	Outputs    []OutputConfig `yaml:"outputs" json:"outputs"`
+}
+
+type OutputConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Type is one of console, file, syslog, journald, ship and audit.
+	Type       string     `yaml:"type" json:"type"`
+	Level      string     `yaml:"level" json:"level"`
+	Format     string     `yaml:"format" json:"format"`
+	TimeFormat string     `yaml:"time_format" json:"time_format"`
+	Keys       KeysConfig `yaml:"keys" json:"keys"`
+	// CEF is the header used by cef format.
+	CEF CEFHeader `yaml:"cef" json:"cef"`
+
+	// Stream is used by console output, stdout or stderr.
+	Stream string `yaml:"stream" json:"stream"`
+
+	// Location and Rotation are used by file output, Location is used by audit output too.
+	Location string       `yaml:"location" json:"location"`
+	Rotation FileRotation `yaml:"rotation" json:"rotation"`
+	// Encryption encrypts the records of file output by AES-GCM.
+	Encryption *FileEncryptionConfig `yaml:"encryption" json:"encryption"`
+
+	Syslog   SyslogConfig   `yaml:"syslog" json:"syslog"`
+	Journald JournaldConfig `yaml:"journald" json:"journald"`
+	Ship     ShipConfig     `yaml:"ship" json:"ship"`
+	Audit    AuditConfig    `yaml:"audit" json:"audit"`
+
+	// Route makes the output write only the matched records.
+	Route *RouteConfig `yaml:"route" json:"route"`
+}
+
+// KeysConfig overrides the keys of LocalFormat, empty value keeps the default one.
+type KeysConfig struct {
+	Message string `yaml:"message" json:"message"`
+	Time    string `yaml:"time" json:"time"`
+	Level   string `yaml:"level" json:"level"`
+	Name    string `yaml:"name" json:"name"`
+	Caller  string `yaml:"caller" json:"caller"`
+}
+
+// NewLoggerFromConfig validates cfg and builds a Logger with its outputs,
+// the outputs already built are closed if a later one fails.
+func NewLoggerFromConfig(cfg Config) (logger Logger, err error) {
+	level, err := parseLevelOrDefault(cfg.Level)
+	if err != nil {
+		return Logger{}, err
+	}
+	dupPolicy := DuplicateCollect
+	if cfg.DuplicateKeys != "" {
+		if dupPolicy, err = ParseDuplicatePolicy(cfg.DuplicateKeys); err != nil {
+			return Logger{}, err
+		}
+	}
+	var redactor *Redactor
+	if cfg.Redact != nil {
+		if redactor, err = NewRedactor(*cfg.Redact); err != nil {
+			return Logger{}, err
+		}
+	}
+	if len(cfg.Outputs) == 0 {
+		return Logger{}, errors.New("log: no outputs configured")
+	}
+	names := make(map[string]bool, len(cfg.Outputs))
+	outputs := make([]Output, 0, len(cfg.Outputs))
+	defer func() {
+		if err != nil {
+			NewLogger(outputs...).Close()
+		}
+	}()
+	for i, it := range cfg.Outputs {
+		if it.Name != "" {
+			if names[it.Name] {
+				return Logger{}, fmt.Errorf("log: outputs[%d]: duplicated name %q", i, it.Name)
+			}
+			names[it.Name] = true
+		}
+		var route Route
+		if it.Route != nil {
+			if route, err = it.Route.route(); err != nil {
+				return Logger{}, fmt.Errorf("log: outputs[%d] %q: route: %w", i, it.Name, err)
+			}
+		}
+		output, err := makeOutputWithConfig(it)
+		if err != nil {
+			return Logger{}, fmt.Errorf("log: outputs[%d] %q: %w", i, it.Name, err)
+		}
+		if route != nil {
+			output = NewRoutedOutput(output, route)
+		}
+		outputs = append(outputs, output)
+	}
+	logger = NewLogger(outputs...).WithDuplicatePolicy(dupPolicy).WithRedactor(redactor).WithErrorStack(cfg.ErrorStack)
+	logger.SetLevel(level)
+	return logger, nil
+}
+
+func makeOutputWithConfig(cfg OutputConfig) (Output, error) {
+	level, err := parseLevelOrDefault(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	format, err := cfg.localFormat()
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(cfg.Type) {
+	case OutputTypeConsole:
+		stream := ConsoleStreamStdout
+		if cfg.Stream != "" {
+			if stream, err = ParseConsoleStream(cfg.Stream); err != nil {
+				return nil, err
+			}
+		}
+		return MakeConsoleOutput(cfg.Name, format, level, stream), nil
+	case OutputTypeFile:
+		if cfg.Location == "" {
+			return nil, errors.New("log: location is required by file output")
+		}
+		if err := cfg.Rotation.validate(); err != nil {
+			return nil, err
+		}
+		if cfg.Encryption != nil {
+			enc, err := cfg.Encryption.encryption()
+			if err != nil {
+				return nil, err
+			}
+			return MakeEncryptedFileOutput(cfg.Name, format, level, cfg.Location, cfg.Rotation, enc), nil
+		}
+		return MakeFileOutput(cfg.Name, format, level, cfg.Location, cfg.Rotation), nil
+	case OutputTypeSyslog:
+		return MakeSyslogOutput(cfg.Name, format, level, cfg.Syslog)
+	case OutputTypeJournald:
+		return MakeJournaldOutput(cfg.Name, format, level, cfg.Journald)
+	case OutputTypeShip:
+		return MakeShipOutput(cfg.Name, format, level, cfg.Ship)
+	case OutputTypeAudit:
+		if cfg.Location == "" {
+			return nil, errors.New("log: location is required by audit output")
+		}
+		key, err := cfg.Audit.key()
+		if err != nil {
+			return nil, err
+		}
+		return MakeAuditOutput(cfg.Name, format, level, cfg.Location, key)
+	case "":
+		return nil, errors.New("log: output type is required")
+	default:
+		return nil, fmt.Errorf("log: unknown output type %q", cfg.Type)
+	}
+}
+
+func (cfg OutputConfig) localFormat() (LocalFormat, error) {
+	msgFormat := MessageFormatJSON
+	if cfg.Format != "" {
+		f, err := ParseMessageFormat(cfg.Format)
+		if err != nil {
+			return LocalFormat{}, err
+		}
+		msgFormat = f
+	}
+	format := MakeLocalFormat(msgFormat)
+	if cfg.TimeFormat != "" {
+		f, err := ParseTimeFormat(cfg.TimeFormat)
+		if err != nil {
+			return LocalFormat{}, err
+		}
+		format.TimeFormat = f
+	}
+	format.CEF = cfg.CEF
+	keys := cfg.Keys
+	for _, it := range []struct {
+		key *string
+		v   string
+	}{
+		{&format.MessageKey, keys.Message},
+		{&format.TimeKey, keys.Time},
+		{&format.LevelKey, keys.Level},
+		{&format.NameKey, keys.Name},
+		{&format.CallerKey, keys.Caller},
+	} {
+		if it.v != "" {
+			*it.key = it.v
+		}
+	}
+	return format, nil
+}
+
+func parseLevelOrDefault(name string) (Level, error) {
+	if name == "" {
+		return LevelDebug, nil
+	}
+	return ParseLevel(name)
+}
+
+// jsonDuration decodes time.Duration from strings like "1s" in JSON, integers are still taken as nanoseconds.
+// YAML decodes such strings into time.Duration itself.
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("log: invalid duration %s", data)
+		}
+		*d = jsonDuration(v)
+		return nil
+	}
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("log: invalid duration %s", data)
+	}
+	*d = jsonDuration(n)
+	return nil
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoggerFromConfig(t *testing.T) {
+	raw := `{
+		"level": "info",
+		"duplicate_keys": "suffix",
+		"outputs": [
+			{"name": "console", "type": "console", "level": "warn", "format": "text", "stream": "stderr"},
+			{"name": "file", "type": "file", "time_format": "millis", "keys": {"message": "message"},
+			 "rotation": {"max_size": 10, "rotate_on_time": true, "rotate_period": "h", "rotate_after": 1}}
+		]
+	}`
+	var cfg Config
+	assert.Nil(t, json.Unmarshal([]byte(raw), &cfg))
+	cfg.Outputs[1].Location = filepath.Join(t.TempDir(), "app.log")
+
+	logger, err := NewLoggerFromConfig(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, LevelInfo, logger.Level())
+	assert.Equal(t, DuplicateSuffix, logger.dupPolicy)
+	assert.Equal(t, 2, len(logger.outputs))
+	assert.Equal(t, LevelWarn, logger.outputs[0].Level())
+	assert.Equal(t, LevelDebug, logger.outputs[1].Level())
+
+	format, err := cfg.Outputs[1].localFormat()
+	assert.Nil(t, err)
+	assert.Equal(t, "message", format.MessageKey)
+	assert.Equal(t, "ts", format.TimeKey)
+	assert.Equal(t, TimeFormatMillis, format.TimeFormat)
+	assert.Nil(t, logger.Close())
+}
+
+func TestNewLoggerFromConfigErrors(t *testing.T) {
+	bad := []Config{
+		{},
+		{Level: "verbose", Outputs: []OutputConfig{{Type: "console"}}},
+		{Outputs: []OutputConfig{{}}},
+		{DuplicateKeys: "merge", Outputs: []OutputConfig{{Type: "console"}}},
+		{Outputs: []OutputConfig{{Type: "kafka"}}},
+		{Outputs: []OutputConfig{{Type: "console", Level: "trace"}}},
+		{Outputs: []OutputConfig{{Type: "console", Format: "xml"}}},
+		{Outputs: []OutputConfig{{Type: "console", TimeFormat: "unix"}}},
+		{Outputs: []OutputConfig{{Type: "console", Stream: "stdin"}}},
+		{Outputs: []OutputConfig{{Type: "file"}}},
+		{Outputs: []OutputConfig{{Type: "file", Location: "a.log", Rotation: FileRotation{RotateOnTime: true, RotatePeriod: "week", RotateAfter: 1}}}},
+		{Outputs: []OutputConfig{{Type: "file", Location: "a.log", Rotation: FileRotation{RotateOnTime: true, RotatePeriod: "d"}}}},
+		{Outputs: []OutputConfig{{Name: "a", Type: "console"}, {Name: "a", Type: "console"}}},
+	}
+	for i, cfg := range bad {
+		_, err := NewLoggerFromConfig(cfg)
+		assert.NotNil(t, err, "config %d", i)
+	}
+}
+
+func TestNewLoggerFromConfigClosesBuiltOutputs(t *testing.T) {
+	dir := t.TempDir()
+	audit := filepath.Join(dir, "audit.log")
+	goroutines := runtime.NumGoroutine()
+	_, err := NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "file", Location: filepath.Join(dir, "app.log"), Rotation: FileRotation{RotateOnTime: true, RotatePeriod: "day", RotateAfter: 1}},
+		{Type: "audit", Location: audit},
+		{Type: "kafka"},
+	}})
+	assert.NotNil(t, err)
+	// the rotation scheduler is stopped
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+	// the audit file is closed
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	for _, it := range fds {
+		target, _ := os.Readlink(filepath.Join("/proc/self/fd", it.Name()))
+		assert.NotEqual(t, audit, target)
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+var defaultLogger atomic.Pointer[Logger]
+
+// SetDefault sets the Logger used when there is no Logger in the context.
+func SetDefault(l Logger) {
+	defaultLogger.Store(&l)
+}
+
+// Default returns the Logger set by SetDefault, it is a Logger without outputs if not set.
+func Default() Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return *l
+	}
+	return NewLogger()
+}
+
+// WithContext returns the Logger in ctx, or the Default one.
+func WithContext(ctx context.Context) Logger {
+	return FromContextOr(ctx, Default())
+}
+
+// FromContext returns the Logger in ctx and whether it exists.
+func FromContext(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return Logger{}, false
+	}
+	l, ok := ctx.Value(contextKey{}).(Logger)
+	return l, ok
+}
+
+// FromContextOr returns the Logger in ctx, or fallback.
+func FromContextOr(ctx context.Context, fallback Logger) Logger {
+	if l, ok := FromContext(ctx); ok {
+		return l
+	}
+	return fallback
+}
+
+func (l Logger) WrapContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// ContextWith returns a context with the Logger of ctx tracing pairs too,
+// so all logs by WithContext of the returned context carry them.
+func ContextWith(ctx context.Context, pairs ...LogPair) context.Context {
+	return WithContext(ctx).WithTraceLogs(pairs...).WrapContext(ctx)
+}
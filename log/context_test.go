@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, WithContext(ctx).IsEmpty())
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+
+	fallback := NewRecorder("fallback", LevelDebug)
+	SetDefault(NewLogger(fallback))
+	defer defaultLogger.Store(nil)
+	WithContext(ctx).Info("default")
+	assert.Equal(t, []string{"default"}, fallback.Subjects())
+
+	// plain string key of other packages does not collide
+	ctx = context.WithValue(ctx, "logger", "other")
+	r := NewRecorder("", LevelDebug)
+	ctx = NewLogger(r).WrapContext(ctx)
+	ctx = ContextWith(ctx, String("user", "u1"))
+	ctx = ContextWith(ctx, String("order", "o1"))
+	WithContext(ctx).Info("scoped")
+	r.AssertContains(t, "scoped", "user", "order")
+
+	FromContextOr(context.Background(), NewLogger(r)).Info("or")
+	assert.Equal(t, []string{"scoped", "or"}, r.Subjects())
+	assert.Equal(t, []string{"default"}, fallback.Subjects())
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// the pairs start at this column if the subject is shorter
+	devSubjectWidth = 40
+	devIndent       = "    "
+
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+var (
+	devBufferPool = buffer.NewPool()
+	// the timestamps of dev format are relative to it
+	devStartTime = time.Now()
+)
+
+var devLevelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel: "\x1b[35m",
+	zapcore.InfoLevel:  "\x1b[34m",
+	zapcore.WarnLevel:  "\x1b[33m",
+	zapcore.ErrorLevel: "\x1b[31m",
+	zapcore.FatalLevel: "\x1b[1;31m",
+}
+
+// devEncoder renders a record in one line for reading locally:
+//
+//	+1.234s INFO  name subject    key=value key=value  file.go:12
+//
+// The time is relative to the start of the process, TimeFormat is ignored.
+// The multi-line values, e.g. Stack, are printed after the line with indention.
+type devEncoder struct {
+	*fieldEncoder
+	cfg   zapcore.EncoderConfig
+	color bool
+}
+
+func newDevEncoder(cfg zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	return devEncoder{fieldEncoder: &fieldEncoder{}, cfg: cfg, color: color}
+}
+
+// isColorTerminal returns false if f is not a terminal or NO_COLOR is set.
+func isColorTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (e devEncoder) Clone() zapcore.Encoder {
+	e.fieldEncoder = e.fieldEncoder.clone()
+	return e
+}
+
+func (e devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.fieldEncoder.clone()
+	for _, it := range fields {
+		it.AddTo(enc)
+	}
+
+	buf := devBufferPool.Get()
+	if e.cfg.TimeKey != "" {
+		e.colored(buf, ansiDim, fmt.Sprintf("%9s", devElapsed(ent.Time)))
+		buf.AppendByte(' ')
+	}
+	if e.cfg.LevelKey != "" {
+		e.colored(buf, devLevelColors[ent.Level], fmt.Sprintf("%-5s", ent.Level.CapitalString()))
+		buf.AppendByte(' ')
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		buf.AppendString(ent.LoggerName)
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(ent.Message)
+
+	var multiLines []encodedField
+	inline := 0
+	for _, it := range enc.fields {
+		if it.str && strings.Contains(it.value, "\n") {
+			multiLines = append(multiLines, it)
+			continue
+		}
+		if inline == 0 {
+			if n := len(ent.Message); n < devSubjectWidth {
+				buf.AppendString(strings.Repeat(" ", devSubjectWidth-n))
+			}
+		}
+		inline++
+		buf.AppendByte(' ')
+		e.colored(buf, ansiCyan, it.key+"=")
+		if it.str {
+			buf.AppendString(devQuote(it.value))
+		} else {
+			buf.AppendString(it.value)
+		}
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		buf.AppendString("  ")
+		e.colored(buf, ansiDim, ent.Caller.TrimmedPath())
+	}
+	buf.AppendByte('\n')
+
+	for _, it := range multiLines {
+		e.appendMultiLine(buf, it.key, it.value)
+	}
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" {
+		e.appendMultiLine(buf, e.cfg.StacktraceKey, ent.Stack)
+	}
+	return buf, nil
+}
+
+func (e devEncoder) appendMultiLine(buf *buffer.Buffer, key, value string) {
+	buf.AppendString(devIndent)
+	e.colored(buf, ansiCyan, key+":")
+	buf.AppendByte('\n')
+	for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+		buf.AppendString(devIndent + devIndent)
+		buf.AppendString(line)
+		buf.AppendByte('\n')
+	}
+}
+
+func (e devEncoder) colored(buf *buffer.Buffer, color, s string) {
+	if !e.color || color == "" {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(ansiReset)
+}
+
+// devElapsed is like +1.234s, or +1h2m3s after an hour.
+func devElapsed(t time.Time) string {
+	d := t.Sub(devStartTime)
+	if d < 0 {
+		d = 0
+	}
+	if d < time.Hour {
+		return "+" + strconv.FormatFloat(d.Seconds(), 'f', 3, 64) + "s"
+	}
+	return "+" + d.Truncate(time.Second).String()
+}
+
+// devQuote quotes the empty string and the ones with spaces, quotes, equal signs or control characters.
+func devQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == '"' || r == '=' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDevEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(newZapLogger("app", MakeLocalFormat(MessageFormatDev), LevelDebug, newZapWriter(buf)))
+	l.WithTraceLogs(String("trace_id", "abc")).Info("hello",
+		String("user", "tom cat"), Int("n", 2), String("empty", ""), Error(errors.New("boom")),
+		Stack([]byte("goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10\n")))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, 6, len(lines))
+	assert.Regexp(t, regexp.MustCompile(`^ *\+\d+\.\d{3}s INFO  app hello {35} trace_id=abc user="tom cat" n=2 empty="" error=boom  log/dev_encoder_test\.go:\d+$`), lines[0])
+	assert.Equal(t, "    stack:", lines[1])
+	assert.Equal(t, "        goroutine 1 [running]:", lines[2])
+	assert.Equal(t, "        main.main()", lines[3])
+	assert.Equal(t, "        \t/app/main.go:10", lines[4])
+	assert.Equal(t, "", lines[5])
+
+	buf.Reset()
+	l.Warn(strings.Repeat("x", 50), Object("obj", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("a", "b")
+		return nil
+	})))
+	assert.Contains(t, buf.String(), "WARN  app "+strings.Repeat("x", 50)+` obj={"a":"b"}  `)
+}
+
+func TestDevEncoderColor(t *testing.T) {
+	cfg := makeZapEncoderConfig(MakeLocalFormat(MessageFormatDev))
+	cfg.CallerKey = ""
+	enc := newDevEncoder(cfg, true)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed", Time: devStartTime.Add(1500 * time.Millisecond)},
+		[]zapcore.Field{String("k", "v").zapField("k")})
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[2m  +1.500s\x1b[0m \x1b[31mERROR\x1b[0m failed"+strings.Repeat(" ", 34)+" \x1b[36mk=\x1b[0mv\n", buf.String())
+
+	assert.Equal(t, "+1h2m3s", devElapsed(devStartTime.Add(time.Hour+2*time.Minute+3500*time.Millisecond)))
+	assert.Equal(t, `"a=b"`, devQuote("a=b"))
+	assert.Equal(t, `"\x1b"`, devQuote("\x1b"))
+}
+
+func TestDevConsoleOutput(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "console")
+	assert.Nil(t, err)
+	defer f.Close()
+	// a regular file is not a terminal
+	assert.False(t, isColorTerminal(f))
+	assert.Equal(t, MessageFormatDev, MakeMessageFormat("dev"))
+	format, err := ParseMessageFormat("DEV")
+	assert.Nil(t, err)
+	assert.Equal(t, MessageFormatDev, format)
+}
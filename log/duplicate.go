@@ -0,0 +1,105 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DuplicatePolicy decides how to log the pairs with the same key,
+// e.g. a key both in the trace and at the call site.
+type DuplicatePolicy int
+
+const (
+	// DuplicateCollect logs the values in order as an array under the key.
+	DuplicateCollect DuplicatePolicy = iota
+	// DuplicateKeepFirst logs the first value only.
+	DuplicateKeepFirst
+	// DuplicateKeepLast logs the last value at the position of the first one.
+	DuplicateKeepLast
+	// DuplicateSuffix logs all the pairs, the later keys are suffixed with _1, _2...
+	DuplicateSuffix
+)
+
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateCollect:
+		return "collect"
+	case DuplicateKeepFirst:
+		return "first"
+	case DuplicateKeepLast:
+		return "last"
+	case DuplicateSuffix:
+		return "suffix"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseDuplicatePolicy(raw string) (DuplicatePolicy, error) {
+	switch strings.ToLower(raw) {
+	case "collect":
+		return DuplicateCollect, nil
+	case "first":
+		return DuplicateKeepFirst, nil
+	case "last":
+		return DuplicateKeepLast, nil
+	case "suffix":
+		return DuplicateSuffix, nil
+	default:
+		return DuplicateCollect, fmt.Errorf("log: unknown duplicate policy %q", raw)
+	}
+}
+
+// dedup handles the duplicated keys of pairs in place and keeps the order of first appearance.
+func (p DuplicatePolicy) dedup(pairs []LogPair) []LogPair {
+	if !hasDuplicateKeys(pairs) {
+		return pairs
+	}
+	indexes := make(map[string]int, len(pairs))
+	collected := make(map[string]bool)
+	suffixes := make(map[string]int)
+	toLog := pairs[:0]
+	for _, it := range pairs {
+		i, exists := indexes[it.key]
+		if !exists {
+			indexes[it.key] = len(toLog)
+			toLog = append(toLog, it)
+			continue
+		}
+		switch p {
+		case DuplicateKeepFirst:
+		case DuplicateKeepLast:
+			toLog[i] = it
+		case DuplicateSuffix:
+			key := it.key
+			for exists {
+				suffixes[it.key]++
+				key = it.key + "_" + strconv.Itoa(suffixes[it.key])
+				_, exists = indexes[key]
+			}
+			indexes[key] = len(toLog)
+			it.key = key
+			toLog = append(toLog, it)
+		default:
+			if collected[it.key] {
+				toLog[i].value = append(toLog[i].value.([]interface{}), it.Value())
+			} else {
+				toLog[i] = Any(it.key, []interface{}{toLog[i].Value(), it.Value()})
+				collected[it.key] = true
+			}
+		}
+	}
+	return toLog
+}
+
+func hasDuplicateKeys(pairs []LogPair) bool {
+	for i := 1; i < len(pairs); i++ {
+		for j := 0; j < i; j++ {
+			if pairs[i].key == pairs[j].key {
+				return true
+			}
+		}
+	}
+	return false
+}
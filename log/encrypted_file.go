@@ -0,0 +1,321 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/byte-power/go-utility/crypto"
+)
+
+// FileEncryption encrypts the records of file output.
+type FileEncryption struct {
+	// Coder should be an authenticated cipher, e.g. crypto.NewAESCoderWithGCM.
+	// If it's a crypto.AEADCoder, every line is bound to a stream id and its sequence number,
+	// so NewDecryptReader finds the lines dropped, reordered or copied from other files.
+	Coder crypto.Coder
+	// BlockSize is the bytes of records encrypted together, 0 encrypts every record alone.
+	// The records of an incomplete block are written every FlushInterval, and by Sync and Close.
+	BlockSize int
+	// FlushInterval is one second by default.
+	FlushInterval time.Duration
+}
+
+// FileEncryptionConfig describes FileEncryption of AES-GCM.
+type FileEncryptionConfig struct {
+	// KeyFile is the file of the AES key in hex, 16, 24 or 32 bytes.
+	KeyFile       string        `yaml:"key_file" json:"key_file"`
+	BlockSize     int           `yaml:"block_size" json:"block_size"`
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+}
+
+// UnmarshalJSON accepts flush_interval as a string like "1s", or integer nanoseconds.
+func (cfg *FileEncryptionConfig) UnmarshalJSON(data []byte) error {
+	type plain FileEncryptionConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		FlushInterval *jsonDuration `json:"flush_interval"`
+	}{plain: (*plain)(cfg), FlushInterval: (*jsonDuration)(&cfg.FlushInterval)})
+}
+
+func (cfg FileEncryptionConfig) encryption() (FileEncryption, error) {
+	if cfg.KeyFile == "" {
+		return FileEncryption{}, errors.New("log: key_file is required by encryption")
+	}
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return FileEncryption{}, err
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return FileEncryption{}, fmt.Errorf("log: key_file: %w", err)
+	}
+	coder, err := crypto.NewAESCoderWithGCM(key)
+	if err != nil {
+		return FileEncryption{}, fmt.Errorf("log: key_file: %w", err)
+	}
+	return FileEncryption{Coder: coder, BlockSize: cfg.BlockSize, FlushInterval: cfg.FlushInterval}, nil
+}
+
+// MakeEncryptedFileOutput is MakeFileOutput writing the records encrypted by enc.Coder,
+// every record or block is written as a line of base64, so the files are rotated without breaking them.
+// The records are read back by NewDecryptReader.
+//
+// With a crypto.AEADCoder, a line is the stream id and the sequence number followed by the cipher text,
+// and both are authenticated as the additional data. The stream id is random for every output,
+// the sequence number starts at 0 and goes on across the rotated files.
+func MakeEncryptedFileOutput(name string, fmt LocalFormat, level Level, location string, rotation FileRotation, enc FileEncryption) Output {
+	writer := &encryptedWriter{FileEncryption: enc, writer: newZapFileWriter(location, rotation)}
+	if aead, ok := enc.Coder.(crypto.AEADCoder); ok {
+		writer.aead = aead
+		rand.Read(writer.stream[:])
+	}
+	if enc.BlockSize > 0 {
+		if writer.FlushInterval <= 0 {
+			writer.FlushInterval = defaultEncryptionFlushInterval
+		}
+		writer.stop = make(chan struct{})
+		writer.done = make(chan struct{})
+		go writer.runFlush()
+	}
+	output := newZapLogger(name, fmt, level, writer)
+	output.closer = writer
+	return output
+}
+
+const (
+	defaultEncryptionFlushInterval = time.Second
+
+	// encryptedStreamSize is the size of the stream id, followed by the sequence number of 8 bytes.
+	encryptedStreamSize = 8
+	encryptedHeaderSize = encryptedStreamSize + 8
+)
+
+type encryptedWriter struct {
+	FileEncryption
+	writer *zapFileWriter
+	// aead, stream and seq are set if Coder is an AEADCoder
+	aead   crypto.AEADCoder
+	stream [encryptedStreamSize]byte
+	seq    uint64
+
+	mu    sync.Mutex
+	block []byte
+	// flushErr is the error of flushing by runFlush, returned by the next Write, Sync or Close
+	flushErr error
+
+	// stop and done are set for the flushing goroutine of blocks
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// runFlush writes the incomplete block every FlushInterval, so a quiet service doesn't keep records in memory.
+func (w *encryptedWriter) runFlush() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil {
+				w.flushErr = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.BlockSize <= 0 {
+		if err := w.writeEncrypted(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	w.block = append(w.block, p...)
+	if len(w.block) >= w.BlockSize {
+		if err := w.flush(); err != nil {
+			// p is kept in the block and written by the next flush
+			return len(p), err
+		}
+	}
+	return len(p), w.takeFlushErr()
+}
+
+// flush keeps the block if it's not written, should be called with mu locked.
+func (w *encryptedWriter) flush() error {
+	if len(w.block) == 0 {
+		return nil
+	}
+	if err := w.writeEncrypted(w.block); err != nil {
+		return err
+	}
+	w.block = w.block[:0]
+	return nil
+}
+
+// takeFlushErr returns flushErr once, should be called with mu locked.
+func (w *encryptedWriter) takeFlushErr() error {
+	err := w.flushErr
+	w.flushErr = nil
+	return err
+}
+
+// writeEncrypted should be called with mu locked.
+func (w *encryptedWriter) writeEncrypted(plain []byte) error {
+	var encrypted []byte
+	if w.aead == nil {
+		var err error
+		if encrypted, err = w.Coder.Encrypt(plain); err != nil {
+			return err
+		}
+	} else {
+		header := make([]byte, encryptedHeaderSize)
+		copy(header, w.stream[:])
+		binary.BigEndian.PutUint64(header[encryptedStreamSize:], w.seq)
+		sealed, err := w.aead.EncryptWithData(plain, header)
+		if err != nil {
+			return err
+		}
+		encrypted = append(header, sealed...)
+	}
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(encrypted))+1)
+	base64.StdEncoding.Encode(line, encrypted)
+	line[len(line)-1] = '\n'
+	if _, err := w.writer.Write(line); err != nil {
+		return err
+	}
+	w.seq++
+	return nil
+}
+
+func (w *encryptedWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return errors.Join(w.takeFlushErr(), w.writer.Sync())
+}
+
+func (w *encryptedWriter) Close() error {
+	if w.stop != nil {
+		w.stopOnce.Do(func() {
+			close(w.stop)
+			<-w.done
+		})
+	}
+	w.mu.Lock()
+	err := w.flush()
+	if err == nil {
+		err = w.takeFlushErr()
+	}
+	w.mu.Unlock()
+	return errors.Join(err, w.writer.Close())
+}
+
+// -------------------------------
+
+type decryptReader struct {
+	reader *bufio.Reader
+	coder  crypto.Coder
+	aead   crypto.AEADCoder
+	line   int
+	plain  []byte
+	err    error
+
+	// stream and seq are of the last line decrypted by aead
+	stream []byte
+	seq    uint64
+}
+
+// NewDecryptReader returns a reader of the plain records of the encrypted file r,
+// e.g. io.Copy(os.Stdout, NewDecryptReader(file, coder)).
+// It fails at the first line which is not decrypted by coder, the line number is in the error.
+//
+// With a crypto.AEADCoder, it fails at the lines out of sequence too: the sequence goes on in a stream,
+// and a new stream starts at 0, e.g. the process is restarted. The first line of r may be of any sequence,
+// since the sequence goes on across the rotated files, so the lines removed from the beginning
+// or the end of a file are not found.
+func NewDecryptReader(r io.Reader, coder crypto.Coder) io.Reader {
+	aead, _ := coder.(crypto.AEADCoder)
+	return &decryptReader{reader: bufio.NewReader(r), coder: coder, aead: aead}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.readLine()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *decryptReader) readLine() {
+	line, err := r.reader.ReadBytes('\n')
+	if err != nil {
+		r.err = err
+	}
+	r.line++
+	line = bytes.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return
+	}
+	encrypted := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+	n, decodeErr := base64.StdEncoding.Decode(encrypted, line)
+	if decodeErr != nil {
+		r.err = fmt.Errorf("log: encrypted line %d: %w", r.line, decodeErr)
+		return
+	}
+	plain, decryptErr := r.decrypt(encrypted[:n])
+	if decryptErr != nil {
+		r.err = fmt.Errorf("log: encrypted line %d: %w", r.line, decryptErr)
+		return
+	}
+	r.plain = plain
+}
+
+func (r *decryptReader) decrypt(encrypted []byte) ([]byte, error) {
+	if r.aead == nil {
+		return r.coder.Decrypt(encrypted)
+	}
+	if len(encrypted) < encryptedHeaderSize {
+		return nil, crypto.ErrCipherTextTooShort
+	}
+	header := encrypted[:encryptedHeaderSize]
+	plain, err := r.aead.DecryptWithData(encrypted[encryptedHeaderSize:], header)
+	if err != nil {
+		return nil, err
+	}
+	stream, seq := header[:encryptedStreamSize], binary.BigEndian.Uint64(header[encryptedStreamSize:])
+	switch {
+	case r.stream == nil:
+	case bytes.Equal(stream, r.stream):
+		if seq != r.seq+1 {
+			return nil, fmt.Errorf("sequence %d follows %d", seq, r.seq)
+		}
+	case seq != 0:
+		return nil, fmt.Errorf("new stream starts at sequence %d", seq)
+	}
+	r.stream, r.seq = stream, seq
+	return plain, nil
+}
@@ -0,0 +1,219 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/byte-power/go-utility/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func readDecrypted(t *testing.T, location string, coder crypto.Coder) ([]string, error) {
+	file, err := os.Open(location)
+	assert.Nil(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(NewDecryptReader(file, coder))
+	var subjects []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record))
+		subjects = append(subjects, record["msg"].(string))
+	}
+	return subjects, err
+}
+
+func TestEncryptedFileOutput(t *testing.T) {
+	coder, err := crypto.NewAESCoderWithGCM([]byte("1234567890abcdef"))
+	assert.Nil(t, err)
+	for _, blockSize := range []int{0, 300} {
+		location := filepath.Join(t.TempDir(), "app.log")
+		l := NewLogger(MakeEncryptedFileOutput("enc", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, FileRotation{},
+			FileEncryption{Coder: coder, BlockSize: blockSize}))
+		for _, it := range []string{"first", "second", "third", "fourth"} {
+			l.Info(it, String("ssn", "123-45-6789"))
+		}
+		assert.Nil(t, l.Close())
+
+		data, err := os.ReadFile(location)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(data), "123-45-6789")
+		assert.NotContains(t, string(data), "first")
+		lines := strings.Count(string(data), "\n")
+		if blockSize == 0 {
+			assert.Equal(t, 4, lines)
+		} else {
+			assert.True(t, lines > 0 && lines < 4)
+		}
+
+		subjects, err := readDecrypted(t, location, coder)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first", "second", "third", "fourth"}, subjects)
+	}
+}
+
+func TestEncryptedFileFlushInterval(t *testing.T) {
+	coder, err := crypto.NewAESCoderWithGCM([]byte("1234567890abcdef"))
+	assert.Nil(t, err)
+	location := filepath.Join(t.TempDir(), "app.log")
+	l := NewLogger(MakeEncryptedFileOutput("enc", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, FileRotation{},
+		FileEncryption{Coder: coder, BlockSize: 1 << 20, FlushInterval: 10 * time.Millisecond}))
+	l.Info("quiet")
+	// the incomplete block is written without Sync
+	assert.Eventually(t, func() bool {
+		if _, err := os.Stat(location); err != nil {
+			return false
+		}
+		subjects, err := readDecrypted(t, location, coder)
+		return err == nil && len(subjects) == 1 && subjects[0] == "quiet"
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Nil(t, l.Close())
+	assert.Nil(t, l.Close())
+}
+
+// failingCoder fails to encrypt while fail is set.
+type failingCoder struct {
+	crypto.Coder
+	fail atomic.Bool
+}
+
+func (c *failingCoder) Encrypt(src []byte) ([]byte, error) {
+	if c.fail.Load() {
+		return nil, errors.New("encrypt failed")
+	}
+	return c.Coder.Encrypt(src)
+}
+
+func TestEncryptedFileFlushError(t *testing.T) {
+	gcm, err := crypto.NewAESCoderWithGCM([]byte("1234567890abcdef"))
+	assert.Nil(t, err)
+	coder := &failingCoder{Coder: gcm}
+	location := filepath.Join(t.TempDir(), "app.log")
+	output := MakeEncryptedFileOutput("enc", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, FileRotation{},
+		FileEncryption{Coder: coder, BlockSize: 1 << 20, FlushInterval: 10 * time.Millisecond})
+	writer := output.(zapOutput).closer.(*encryptedWriter)
+	l := NewLogger(output)
+
+	coder.fail.Store(true)
+	l.Info("first")
+	// the error of the timer flush is kept for the next Sync
+	assert.Eventually(t, func() bool {
+		writer.mu.Lock()
+		defer writer.mu.Unlock()
+		return writer.flushErr != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	coder.fail.Store(false)
+	assert.ErrorContains(t, l.Sync(), "encrypt failed")
+	assert.Nil(t, l.Sync())
+
+	coder.fail.Store(true)
+	l.Info("second")
+	assert.ErrorContains(t, l.Close(), "encrypt failed")
+
+	// the block failed is not lost
+	subjects, err := readDecrypted(t, location, coder)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first"}, subjects)
+}
+
+func TestDecryptReaderErrors(t *testing.T) {
+	coder, _ := crypto.NewAESCoderWithGCM([]byte("1234567890abcdef"))
+	other, _ := crypto.NewAESCoderWithGCM([]byte("abcdef1234567890"))
+	location := filepath.Join(t.TempDir(), "app.log")
+	l := NewLogger(MakeEncryptedFileOutput("enc", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, FileRotation{}, FileEncryption{Coder: coder}))
+	l.Info("first")
+	l.Info("second")
+	assert.Nil(t, l.Close())
+
+	_, err := readDecrypted(t, location, other)
+	assert.ErrorContains(t, err, "log: encrypted line 1:")
+
+	data, _ := os.ReadFile(location)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	_, err = io.ReadAll(NewDecryptReader(bytes.NewReader(append(append([]byte(nil), lines[0]...), "bm90IGVuY3J5cHRlZA==\n"...)), coder))
+	assert.ErrorContains(t, err, "log: encrypted line 2:")
+	_, err = io.ReadAll(NewDecryptReader(strings.NewReader("!!!\n"), coder))
+	assert.ErrorContains(t, err, "log: encrypted line 1:")
+}
+
+func TestDecryptReaderSequence(t *testing.T) {
+	coder, _ := crypto.NewAESCoderWithGCM([]byte("1234567890abcdef"))
+	writeLines := func(subjects ...string) [][]byte {
+		location := filepath.Join(t.TempDir(), "app.log")
+		l := NewLogger(MakeEncryptedFileOutput("enc", MakeLocalFormat(MessageFormatJSON), LevelInfo, location, FileRotation{}, FileEncryption{Coder: coder}))
+		for _, it := range subjects {
+			l.Info(it)
+		}
+		assert.Nil(t, l.Close())
+		data, _ := os.ReadFile(location)
+		return bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	}
+	lines := writeLines("first", "second", "third")
+	restarted := writeLines("restarted", "again")
+	read := func(lines ...[]byte) (string, error) {
+		data, err := io.ReadAll(NewDecryptReader(bytes.NewReader(bytes.Join(lines, []byte("\n"))), coder))
+		return string(data), err
+	}
+
+	// a rotated file starts at any sequence, and a restarted stream starts at 0
+	data, err := read(lines[1], lines[2], restarted[0], restarted[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 4, strings.Count(data, "\n"))
+
+	_, err = read(lines[0], lines[2])
+	assert.ErrorContains(t, err, "log: encrypted line 2: sequence 2 follows 0")
+	_, err = read(lines[0], lines[2], lines[1])
+	assert.ErrorContains(t, err, "log: encrypted line 2: sequence 2 follows 0")
+	_, err = read(lines[0], restarted[1])
+	assert.ErrorContains(t, err, "log: encrypted line 2: new stream starts at sequence 1")
+	_, err = read(lines[0], restarted[0], lines[1])
+	assert.ErrorContains(t, err, "log: encrypted line 3: new stream starts at sequence 1")
+
+	// the stream and the sequence are authenticated
+	encrypted, _ := base64.StdEncoding.DecodeString(string(lines[2]))
+	encrypted[encryptedHeaderSize-1] = 1
+	_, err = read(lines[0], []byte(base64.StdEncoding.EncodeToString(encrypted)))
+	assert.ErrorContains(t, err, "log: encrypted line 2: cipher: message authentication failed")
+}
+
+func TestEncryptedFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	assert.Nil(t, os.WriteFile(keyFile, []byte("000102030405060708090a0b0c0d0e0f\n"), 0o600))
+	location := filepath.Join(dir, "app.log")
+	var cfg Config
+	raw := `{"outputs": [{"type": "file", "location": "` + location + `",
+		"encryption": {"key_file": "` + keyFile + `", "block_size": 4096, "flush_interval": "2s"}}]}`
+	assert.Nil(t, json.Unmarshal([]byte(raw), &cfg))
+	assert.Equal(t, 2*time.Second, cfg.Outputs[0].Encryption.FlushInterval)
+	logger, err := NewLoggerFromConfig(cfg)
+	assert.Nil(t, err)
+	logger.Info("hello")
+	assert.Nil(t, logger.Close())
+
+	coder, _ := crypto.NewAESCoderWithGCM([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+	subjects, err := readDecrypted(t, location, coder)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hello"}, subjects)
+
+	assert.Nil(t, os.WriteFile(keyFile, []byte("0001"), 0o600))
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "file", Location: location, Encryption: &FileEncryptionConfig{KeyFile: keyFile}},
+	}})
+	assert.NotNil(t, err)
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "file", Location: location, Encryption: &FileEncryptionConfig{}},
+	}})
+	assert.NotNil(t, err)
+}
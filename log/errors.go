@@ -0,0 +1,129 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	fieldStack = "stack"
+	// the causes of an error pair are logged with the key of the pair plus the suffix, e.g. error_causes
+	errorCausesSuffix = "_causes"
+	maxErrorChain     = 32
+)
+
+// ErrorFieldsProvider is implemented by errors carrying their own pairs,
+// the pairs of all errors in the chain are logged with the error pair.
+type ErrorFieldsProvider interface {
+	LogFields() []LogPair
+}
+
+// errorChain is the errors unwrapped depth-first from an error, the errors of errors.Join included.
+type errorChain []error
+
+func unwrapErrorChain(err error) errorChain {
+	var chain errorChain
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil || len(chain) >= maxErrorChain {
+			return
+		}
+		chain = append(chain, err)
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, it := range e.Unwrap() {
+				walk(it)
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+func (c errorChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, it := range c {
+		if err := enc.AppendObject(errorCause{it}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type errorCause struct {
+	err error
+}
+
+func (c errorCause) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("msg", c.err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", c.err))
+	return nil
+}
+
+// expandErrors appends the causes of the wrapped errors and the pairs of ErrorFieldsProvider,
+// pairs is returned as it is if there is nothing to append.
+func expandErrors(pairs []LogPair) []LogPair {
+	var toLog []LogPair
+	for _, it := range pairs {
+		if it.kind() != pairKindError || it.value == nil {
+			continue
+		}
+		chain := unwrapErrorChain(it.value.(error))
+		var fields []LogPair
+		for _, err := range chain {
+			if provider, ok := err.(ErrorFieldsProvider); ok {
+				fields = append(fields, provider.LogFields()...)
+			}
+		}
+		if len(chain) < 2 && len(fields) == 0 {
+			continue
+		}
+		if toLog == nil {
+			toLog = make([]LogPair, len(pairs), len(pairs)+len(fields)+1)
+			copy(toLog, pairs)
+		}
+		if len(chain) > 1 {
+			toLog = append(toLog, Array(it.key+errorCausesSuffix, chain))
+		}
+		toLog = append(toLog, fields...)
+	}
+	if toLog == nil {
+		return pairs
+	}
+	return toLog
+}
+
+// callerStack is like debug.Stack of the current goroutine, but the frames of this package are skipped.
+func callerStack() []byte {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if !isInternalFrame(frame.File, frame.Function) {
+			b.WriteString(frame.Function)
+			b.WriteString("\n\t")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+		}
+		if !more {
+			return []byte(b.String())
+		}
+	}
+}
+
+func hasKey(pairs []LogPair, key string) bool {
+	for _, it := range pairs {
+		if it.key == key {
+			return true
+		}
+	}
+	return false
+}
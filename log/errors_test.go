@@ -0,0 +1,96 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userError struct {
+	user string
+}
+
+func (e userError) Error() string { return "invalid user " + e.user }
+
+func (e userError) LogFields() []LogPair { return []LogPair{String("user", e.user)} }
+
+func TestErrorCauses(t *testing.T) {
+	r := NewRecorder("r", LevelDebug)
+	l := NewLogger(r)
+
+	base := errors.New("connection refused")
+	err := fmt.Errorf("query: %w", fmt.Errorf("dial: %w", base))
+	l.Warn("failed", Error(err))
+	record, _ := r.Last()
+	assert.Equal(t, []string{"error", "error_causes"}, record.Keys())
+	causes, _ := record.Value("error_causes")
+	assert.Equal(t, errorChain{err, errors.Unwrap(err), base}, causes)
+
+	pairs := Array("error_causes", causes.(errorChain)).zapField("error_causes")
+	enc := &fieldEncoder{}
+	pairs.AddTo(enc)
+	assert.Equal(t, `[{"msg":"query: dial: connection refused","type":"*fmt.wrapError"},`+
+		`{"msg":"dial: connection refused","type":"*fmt.wrapError"},{"msg":"connection refused","type":"*errors.errorString"}]`, enc.fields[0].value)
+
+	// errors.Join and fields providers
+	joined := errors.Join(userError{"tom"}, fmt.Errorf("retry: %w", base))
+	l.Error("failed", Error(joined), Int("n", 1))
+	record, _ = r.Last()
+	assert.Equal(t, []string{"error", "n", "error_causes", "user"}, record.Keys())
+	causes, _ = record.Value("error_causes")
+	assert.Equal(t, 4, len(causes.(errorChain)))
+	user, _ := record.Value("user")
+	assert.Equal(t, "tom", user)
+
+	// nothing is added for the plain errors
+	pairs2 := []LogPair{Error(base), Error(nil)}
+	assert.Equal(t, pairs2, expandErrors(pairs2))
+}
+
+func TestErrorChainLimit(t *testing.T) {
+	err := errors.New("root")
+	for i := 0; i < 100; i++ {
+		err = fmt.Errorf("wrap %d: %w", i, err)
+	}
+	assert.Equal(t, maxErrorChain, len(unwrapErrorChain(err)))
+}
+
+func TestErrorStack(t *testing.T) {
+	r := NewRecorder("r", LevelDebug)
+	l := NewLogger(r).WithErrorStack(true)
+	l.Warn("warn")
+	l.Error("error")
+	l.Errorf("error %d", 2)
+	l.Error("own stack", Stack([]byte("mine")))
+
+	records := r.Records()
+	assert.False(t, records[0].Has("stack"))
+	for _, it := range records[1:3] {
+		stack, _ := it.Value("stack")
+		lines := strings.Split(stack.(string), "\n")
+		assert.Equal(t, "github.com/byte-power/go-utility/log.TestErrorStack", lines[0])
+		assert.Contains(t, lines[1], "log/errors_test.go:")
+		assert.NotContains(t, stack, "log/log.go")
+	}
+	stack, _ := records[3].Value("stack")
+	assert.Equal(t, "mine", stack)
+
+	logger, err := NewLoggerFromConfig(Config{ErrorStack: true, Outputs: []OutputConfig{{Type: "console"}}})
+	assert.Nil(t, err)
+	assert.True(t, logger.errorStack)
+}
+
+func TestErrorCausesRedacted(t *testing.T) {
+	redactor, err := NewRedactor(RedactConfig{Rules: []RedactRule{{ValuePatterns: []string{`password=\S+`}}}})
+	assert.Nil(t, err)
+	r := NewRecorder("r", LevelDebug)
+	NewLogger(r).WithRedactor(redactor).Error("failed", Error(fmt.Errorf("login: %w", errors.New("password=123"))))
+	record, _ := r.Last()
+	msg, _ := record.Value("error")
+	assert.Equal(t, "login: ******", msg)
+	causes, _ := record.Value("error_causes")
+	assert.NotContains(t, fmt.Sprint(causes), "123")
+}
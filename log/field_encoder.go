@@ -0,0 +1,167 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// encodedField is a field rendered into a string.
+type encodedField struct {
+	key   string
+	value string
+	// str is true for the string values, which may be quoted by the format,
+	// the values of other types are numbers, bools or JSON.
+	str bool
+}
+
+// fieldEncoder collects the fields in order with their values rendered,
+// it's shared by the encoders of flat formats, objects and arrays are rendered as JSON.
+type fieldEncoder struct {
+	fields []encodedField
+	// prefix of keys in the current namespace, e.g. "a.b."
+	prefix string
+}
+
+var _ zapcore.ObjectEncoder = (*fieldEncoder)(nil)
+
+func (e *fieldEncoder) clone() *fieldEncoder {
+	return &fieldEncoder{fields: append([]encodedField(nil), e.fields...), prefix: e.prefix}
+}
+
+func (e *fieldEncoder) add(key, value string, str bool) {
+	e.fields = append(e.fields, encodedField{key: e.prefix + key, value: value, str: str})
+}
+
+func (e *fieldEncoder) addJSON(key string, add func(zapcore.ObjectEncoder) error) error {
+	enc := zapcore.NewMapObjectEncoder()
+	if err := add(enc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(enc.Fields[key])
+	if err != nil {
+		return err
+	}
+	e.add(key, string(data), false)
+	return nil
+}
+
+func (e *fieldEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddArray(key, v) })
+}
+
+func (e *fieldEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddObject(key, v) })
+}
+
+func (e *fieldEncoder) AddBinary(key string, v []byte) {
+	e.add(key, base64.StdEncoding.EncodeToString(v), true)
+}
+
+func (e *fieldEncoder) AddByteString(key string, v []byte) { e.add(key, string(v), true) }
+
+func (e *fieldEncoder) AddBool(key string, v bool) { e.add(key, strconv.FormatBool(v), false) }
+
+func (e *fieldEncoder) AddComplex128(key string, v complex128) {
+	e.add(key, strconv.FormatComplex(v, 'g', -1, 128), true)
+}
+
+func (e *fieldEncoder) AddComplex64(key string, v complex64) {
+	e.add(key, strconv.FormatComplex(complex128(v), 'g', -1, 64), true)
+}
+
+func (e *fieldEncoder) AddDuration(key string, v time.Duration) { e.add(key, v.String(), true) }
+
+func (e *fieldEncoder) AddFloat64(key string, v float64) {
+	e.add(key, strconv.FormatFloat(v, 'g', -1, 64), false)
+}
+
+func (e *fieldEncoder) AddFloat32(key string, v float32) {
+	e.add(key, strconv.FormatFloat(float64(v), 'g', -1, 32), false)
+}
+
+func (e *fieldEncoder) AddInt(key string, v int)     { e.AddInt64(key, int64(v)) }
+func (e *fieldEncoder) AddInt32(key string, v int32) { e.AddInt64(key, int64(v)) }
+func (e *fieldEncoder) AddInt16(key string, v int16) { e.AddInt64(key, int64(v)) }
+func (e *fieldEncoder) AddInt8(key string, v int8)   { e.AddInt64(key, int64(v)) }
+
+func (e *fieldEncoder) AddInt64(key string, v int64) { e.add(key, strconv.FormatInt(v, 10), false) }
+
+func (e *fieldEncoder) AddString(key, v string) { e.add(key, v, true) }
+
+func (e *fieldEncoder) AddTime(key string, v time.Time) {
+	e.add(key, v.Format(time.RFC3339Nano), true)
+}
+
+func (e *fieldEncoder) AddUint(key string, v uint)       { e.AddUint64(key, uint64(v)) }
+func (e *fieldEncoder) AddUint32(key string, v uint32)   { e.AddUint64(key, uint64(v)) }
+func (e *fieldEncoder) AddUint16(key string, v uint16)   { e.AddUint64(key, uint64(v)) }
+func (e *fieldEncoder) AddUint8(key string, v uint8)     { e.AddUint64(key, uint64(v)) }
+func (e *fieldEncoder) AddUintptr(key string, v uintptr) { e.AddUint64(key, uint64(v)) }
+
+func (e *fieldEncoder) AddUint64(key string, v uint64) {
+	e.add(key, strconv.FormatUint(v, 10), false)
+}
+
+func (e *fieldEncoder) AddReflected(key string, v interface{}) error {
+	if s, ok := v.(string); ok {
+		e.add(key, s, true)
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.add(key, fmt.Sprint(v), true)
+		return nil
+	}
+	e.add(key, string(data), false)
+	return nil
+}
+
+func (e *fieldEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+// primitiveEncoder renders the primitives appended by the encoders of EncoderConfig, e.g. EncodeTime.
+type primitiveEncoder struct {
+	values []string
+}
+
+var _ zapcore.PrimitiveArrayEncoder = (*primitiveEncoder)(nil)
+
+func encodePrimitive(encode func(zapcore.PrimitiveArrayEncoder)) string {
+	enc := &primitiveEncoder{}
+	encode(enc)
+	return strings.Join(enc.values, " ")
+}
+
+func (e *primitiveEncoder) append(v string) { e.values = append(e.values, v) }
+
+func (e *primitiveEncoder) AppendBool(v bool)         { e.append(strconv.FormatBool(v)) }
+func (e *primitiveEncoder) AppendByteString(v []byte) { e.append(string(v)) }
+func (e *primitiveEncoder) AppendComplex128(v complex128) {
+	e.append(strconv.FormatComplex(v, 'g', -1, 128))
+}
+func (e *primitiveEncoder) AppendComplex64(v complex64) {
+	e.append(strconv.FormatComplex(complex128(v), 'g', -1, 64))
+}
+func (e *primitiveEncoder) AppendFloat64(v float64) { e.append(strconv.FormatFloat(v, 'g', -1, 64)) }
+func (e *primitiveEncoder) AppendFloat32(v float32) {
+	e.append(strconv.FormatFloat(float64(v), 'g', -1, 32))
+}
+func (e *primitiveEncoder) AppendInt(v int)         { e.AppendInt64(int64(v)) }
+func (e *primitiveEncoder) AppendInt64(v int64)     { e.append(strconv.FormatInt(v, 10)) }
+func (e *primitiveEncoder) AppendInt32(v int32)     { e.AppendInt64(int64(v)) }
+func (e *primitiveEncoder) AppendInt16(v int16)     { e.AppendInt64(int64(v)) }
+func (e *primitiveEncoder) AppendInt8(v int8)       { e.AppendInt64(int64(v)) }
+func (e *primitiveEncoder) AppendString(v string)   { e.append(v) }
+func (e *primitiveEncoder) AppendUint(v uint)       { e.AppendUint64(uint64(v)) }
+func (e *primitiveEncoder) AppendUint64(v uint64)   { e.append(strconv.FormatUint(v, 10)) }
+func (e *primitiveEncoder) AppendUint32(v uint32)   { e.AppendUint64(uint64(v)) }
+func (e *primitiveEncoder) AppendUint16(v uint16)   { e.AppendUint64(uint64(v)) }
+func (e *primitiveEncoder) AppendUint8(v uint8)     { e.AppendUint64(uint64(v)) }
+func (e *primitiveEncoder) AppendUintptr(v uintptr) { e.AppendUint64(uint64(v)) }
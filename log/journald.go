@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+type JournaldConfig struct {
+	// Socket is the path of journald's native socket, default is /run/systemd/journal/socket.
+	Socket string `yaml:"socket" json:"socket"`
+	// Identifier is SYSLOG_IDENTIFIER, default is the name of the executable.
+	Identifier string `yaml:"identifier" json:"identifier"`
+}
+
+// MakeJournaldOutput makes an Output sending records to journald by its native protocol,
+// MESSAGE is encoded by fmt, and PRIORITY is mapped from the level like syslog.
+// A record should be smaller than the datagram size limit of the socket.
+func MakeJournaldOutput(name string, fmt LocalFormat, level Level, cfg JournaldConfig) (Output, error) {
+	if cfg.Socket == "" {
+		cfg.Socket = defaultJournaldSocket
+	}
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+	w, err := newConnWriter("unixgram", cfg.Socket)
+	if err != nil {
+		return nil, err
+	}
+	framer := journaldFramer{identifier: cfg.Identifier, pid: strconv.Itoa(os.Getpid())}
+	return makeFramedOutput(name, fmt, level, w, framer.frame), nil
+}
+
+type journaldFramer struct {
+	identifier string
+	pid        string
+}
+
+func (f journaldFramer) frame(ent zapcore.Entry, msg []byte) []byte {
+	buf := &bytes.Buffer{}
+	appendJournaldField(buf, "PRIORITY", []byte(strconv.Itoa(syslogSeverity(ent.Level))))
+	appendJournaldField(buf, "SYSLOG_IDENTIFIER", []byte(f.identifier))
+	appendJournaldField(buf, "SYSLOG_PID", []byte(f.pid))
+	if ent.Caller.Defined {
+		appendJournaldField(buf, "CODE_FILE", []byte(ent.Caller.File))
+		appendJournaldField(buf, "CODE_LINE", []byte(strconv.Itoa(ent.Caller.Line)))
+	}
+	appendJournaldField(buf, "MESSAGE", msg)
+	return buf.Bytes()
+}
+
+// appendJournaldField uses the binary format for the value with newlines.
+func appendJournaldField(buf *bytes.Buffer, key string, value []byte) {
+	buf.WriteString(key)
+	if bytes.IndexByte(value, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.Write(value)
+	} else {
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+		buf.Write(value)
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type levelPayload struct {
+	Level   string            `json:"level"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+type levelHandler struct {
+	logger Logger
+}
+
+// NewLevelHandler returns a http.Handler to get or change levels of the logger at runtime.
+// The query parameter "output" selects an output by its name, otherwise the logger itself is used.
+//
+//	GET  /              => {"level":"info","outputs":{"console":"warn"}}
+//	GET  /?output=file  => {"level":"debug"}
+//	PUT  /?output=file  <= {"level":"info"}
+func NewLevelHandler(logger Logger) http.Handler {
+	return levelHandler{logger: logger}
+}
+
+func (h levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("output")
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, name)
+	case http.MethodPut:
+		h.put(w, r, name)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+func (h levelHandler) get(w http.ResponseWriter, name string) {
+	if name != "" {
+		level, ok := h.logger.OutputLevel(name)
+		if !ok {
+			writeLevelError(w, http.StatusNotFound, fmt.Errorf("output %q not found", name))
+			return
+		}
+		writeLevelJSON(w, http.StatusOK, levelPayload{Level: level.String()})
+		return
+	}
+	payload := levelPayload{Level: h.logger.Level().String(), Outputs: make(map[string]string)}
+	for _, it := range h.logger.outputs {
+		if named, ok := it.(NamedOutput); ok && named.Name() != "" {
+			payload.Outputs[named.Name()] = it.Level().String()
+		}
+	}
+	writeLevelJSON(w, http.StatusOK, payload)
+}
+
+func (h levelHandler) put(w http.ResponseWriter, r *http.Request, name string) {
+	var payload levelPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeLevelError(w, http.StatusBadRequest, err)
+		return
+	}
+	level, err := ParseLevel(payload.Level)
+	if err != nil {
+		writeLevelError(w, http.StatusBadRequest, err)
+		return
+	}
+	if name == "" {
+		h.logger.SetLevel(level)
+		writeLevelJSON(w, http.StatusOK, levelPayload{Level: level.String()})
+		return
+	}
+	if _, ok := h.logger.OutputLevel(name); !ok {
+		writeLevelError(w, http.StatusNotFound, fmt.Errorf("output %q not found", name))
+		return
+	}
+	if err := h.logger.SetOutputLevel(name, level); err != nil {
+		writeLevelError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeLevelJSON(w, http.StatusOK, levelPayload{Level: level.String()})
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeLevelError(w http.ResponseWriter, status int, err error) {
+	writeLevelJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(newZapLogger("buf", MakeLocalFormat(MessageFormatJSON), LevelInfo, newZapWriter(buf)))
+	derived := logger.WithTraceLogs(String("a", "b"))
+	handler := NewLevelHandler(logger)
+
+	derived.Debug("hidden")
+	assert.Equal(t, 0, buf.Len())
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"debug","outputs":{"buf":"info"}}`, w.Body.String())
+
+	w = serve(http.MethodPut, "/?output=buf", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	derived.Debug("shown")
+	assert.Contains(t, buf.String(), "shown")
+
+	w = serve(http.MethodPut, "/", `{"level":"error"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, LevelError, derived.Level())
+	buf.Reset()
+	derived.Warn("hidden")
+	assert.Equal(t, 0, buf.Len())
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/?output=none", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/?output=none", `{"level":"info"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/", `{"level":"loud"}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/", "").Code)
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingOutput struct {
+	syncErr  error
+	closeErr error
+}
+
+func (failingOutput) Level() Level                               { return LevelDebug }
+func (failingOutput) LogModuleAndPairs(Level, string, []LogPair) {}
+func (failingOutput) Name() string                               { return "failing" }
+func (o failingOutput) Sync() error                              { return o.syncErr }
+func (o failingOutput) Close() error                             { return o.closeErr }
+
+func TestLoggerSyncAndClose(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "app.log")
+	rotation := FileRotation{RotateOnTime: true, RotatePeriod: "s", RotateAfter: 1}
+	l := NewLogger(MakeFileOutput("file", MakeLocalFormat(MessageFormatJSON), LevelDebug, location, rotation))
+	l.Info("before close")
+	assert.Nil(t, l.Sync())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, l.Close())
+		}()
+	}
+	wg.Wait()
+
+	assert.Nil(t, os.Remove(location))
+	l.Info("after close")
+	_, err := os.Stat(location)
+	assert.True(t, os.IsNotExist(err))
+
+	syncErr, closeErr := errors.New("sync"), errors.New("close")
+	l = NewLogger(failingOutput{syncErr: syncErr, closeErr: closeErr}, failingOutput{})
+	err = l.Close()
+	assert.ErrorIs(t, err, syncErr)
+	assert.ErrorIs(t, err, closeErr)
+	assert.Contains(t, err.Error(), `output "failing"`)
+}
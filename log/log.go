@@ -1,23 +1,30 @@
 package log
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
 	"time"
+
+	"go.uber.org/zap/zapcore"
 )
 
 const (
-	fieldLoggerKey = "logger"
-
 	fieldTraceID       = "trace_id"
 	fieldTraceDuration = "trace_dur"
 )
 
 type Logger struct {
-	level   Level
-	trace   Trace
-	outputs []Output
+	level     *LevelVar
+	trace     Trace
+	dupPolicy DuplicatePolicy
+	redactor  *Redactor
+	// errorStack captures the stack of the call site for LevelError and above
+	errorStack bool
+	outputs    []Output
 }
 
 type Output interface {
@@ -25,8 +32,32 @@ type Output interface {
 	LogModuleAndPairs(l Level, subject string, pairs []LogPair)
 }
 
+// callerOutput is implemented by the outputs logging the caller and the ones wrapping them,
+// pc is the program counter of the caller of Logger, 0 if it's unknown.
+// The caller is captured once by Logger, so it's right behind any wrapping output or goroutine.
+type callerOutput interface {
+	logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair)
+}
+
+func logToOutput(output Output, pc uintptr, l Level, subject string, pairs []LogPair) {
+	if o, ok := output.(callerOutput); ok {
+		o.logWithCaller(pc, l, subject, pairs)
+		return
+	}
+	output.LogModuleAndPairs(l, subject, pairs)
+}
+
+// callerPC returns the program counter of the function skip frames above the caller of callerPC.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
 func NewLogger(outputs ...Output) Logger {
-	return Logger{outputs: outputs, level: LevelDebug}
+	return Logger{outputs: outputs, level: NewLevelVar(LevelDebug)}
 }
 
 func NewLoggerWithTrace(logger *Logger, traceTime time.Time, tracePairs ...LogPair) Logger {
@@ -35,32 +66,109 @@ func NewLoggerWithTrace(logger *Logger, traceTime time.Time, tracePairs ...LogPa
 	return logger.WithTrace(trace)
 }
 
-// DO NOT USE IN SERVER
+// SetLevel changes the level of l and all loggers derived from it,
+// it is safe to be called while logging.
 func (l *Logger) SetLevel(level Level) {
-	l.level = level
+	if l.level == nil {
+		l.level = NewLevelVar(level)
+		return
+	}
+	l.level.Set(level)
+}
+
+func (l Logger) Level() Level {
+	return l.level.Level()
+}
+
+// OutputLevel returns the level of the output named name.
+func (l Logger) OutputLevel(name string) (Level, bool) {
+	output := l.namedOutput(name)
+	if output == nil {
+		return LevelDebug, false
+	}
+	return output.Level(), true
+}
+
+// SetOutputLevel changes the level of the output named name at runtime.
+func (l Logger) SetOutputLevel(name string, level Level) error {
+	output := l.namedOutput(name)
+	if output == nil {
+		return fmt.Errorf("log: output %q not found", name)
+	}
+	if !canSetLevel(output) {
+		return fmt.Errorf("log: level of output %q can not be changed", name)
+	}
+	output.(LevelSetter).SetLevel(level)
+	return nil
+}
+
+func (l Logger) namedOutput(name string) Output {
+	for _, it := range l.outputs {
+		if named, ok := it.(NamedOutput); ok && named.Name() == name {
+			return it
+		}
+	}
+	return nil
 }
 
 func MakeConsoleOutput(name string, fmt LocalFormat, level Level, stream ConsoleStream) Output {
 	writer := newZapConsoleWriter(stream.stream())
+	if fmt.Format == MessageFormatDev && isColorTerminal(stream.stream()) {
+		return newZapLoggerWithCore(name, fmt, level, func(_ zapcore.Encoder, enabler zapcore.LevelEnabler) zapcore.Core {
+			return zapcore.NewCore(newDevEncoder(makeZapEncoderConfig(fmt), true), writer, enabler)
+		})
+	}
 	return newZapLogger(name, fmt, level, writer)
 }
 
+// MakeFileOutput makes an Output writing into location with rotation,
+// the returned Output should be closed to stop the time based rotation and release the file.
 func MakeFileOutput(name string, fmt LocalFormat, level Level, location string, rotation FileRotation) Output {
 	writer := newZapFileWriter(location, rotation)
-	return newZapLogger(name, fmt, level, writer)
+	output := newZapLogger(name, fmt, level, writer)
+	output.closer = writer
+	return output
+}
+
+// MakeWriterOutput makes an Output writing into w, e.g. a pipe, buffer or network connection,
+// writes are serialized so w need not be safe for concurrent use.
+// w is synced by Sync if it has the method, but it's not closed by the Output.
+func MakeWriterOutput(name string, fmt LocalFormat, level Level, w io.Writer) Output {
+	return newZapLogger(name, fmt, level, zapcore.Lock(newZapWriter(w)))
 }
 
-func WithContext(ctx context.Context) Logger {
-	l, _ := ctx.Value(fieldLoggerKey).(Logger)
+func (l Logger) WithTrace(trace Trace) Logger {
+	l.trace = l.trace.merge(trace)
 	return l
 }
 
-func (l Logger) WrapContext(ctx context.Context) context.Context {
-	return context.WithValue(ctx, fieldLoggerKey, l)
+// WithDuplicatePolicy returns a Logger handling the pairs with the same key by p.
+func (l Logger) WithDuplicatePolicy(p DuplicatePolicy) Logger {
+	l.dupPolicy = p
+	return l
 }
 
-func (l Logger) WithTrace(trace Trace) Logger {
-	l.trace = l.trace.merge(trace)
+// StartSpan returns a Logger with a child span of the current trace, see Trace.StartSpan.
+func (l Logger) StartSpan(startTime time.Time, pairs ...LogPair) Logger {
+	l.trace = l.trace.StartSpan(startTime, pairs...)
+	return l
+}
+
+// SpanContext returns the W3C trace context of the trace, e.g. to be injected into outgoing requests.
+func (l Logger) SpanContext() SpanContext {
+	return l.trace.span
+}
+
+// WithRedactor returns a Logger redacting the subject and the pairs, including trace pairs, before written to any output.
+func (l Logger) WithRedactor(r *Redactor) Logger {
+	l.redactor = r
+	return l
+}
+
+// WithErrorStack returns a Logger logging the stack of the call site as the stack pair
+// for the records of LevelError and above, unless they have one already.
+func (l Logger) WithErrorStack(enabled bool) Logger {
+	l.errorStack = enabled
 	return l
 }
 
@@ -71,57 +179,44 @@ func (l Logger) WithTraceLogs(pairs ...LogPair) Logger {
 	return l
 }
 
-// 得到有哪些output可输出指定level的log
-func (l Logger) marchLevelOutputs(level Level) []Output {
-	outputs := make([]Output, 0, len(l.outputs))
-	for _, it := range l.outputs {
-		if level >= it.Level() {
-			outputs = append(outputs, it)
-		}
-	}
-	return outputs
-}
-
 // 产生需要log的数据
-// 如有起始时间，将包含起始时间至此时的时长
-// 如已有pairs与参数pairs之间有重复的key，它们的值将会被合并
+// 顺序为trace的pairs，trace_dur(如有起始时间)，参数pairs
+// 重复的key按DuplicatePolicy处理
 func (l Logger) producePairs(pairs []LogPair) []LogPair {
-	if l.trace.isEmpty() {
+	if l.trace.isEmpty() && !hasDuplicateKeys(pairs) {
 		return pairs
 	}
-	dedup := make(map[string]any, l.trace.length()+len(pairs)+1)
-	for _, v := range l.trace.pairs {
-		dedup[v.key] = v.value
-	}
+	toLog := make([]LogPair, 0, l.trace.length()+len(pairs)+1)
+	toLog = append(toLog, l.trace.pairs...)
 	if !l.trace.startTime.IsZero() {
-		dedup[fieldTraceDuration] = time.Since(l.trace.startTime).String()
-	}
-	for _, v := range pairs {
-		exists := dedup[v.key]
-		if exists != nil {
-			dedup[v.key] = fmt.Sprintf("%v,%v", exists, v.value)
-		} else {
-			dedup[v.key] = v.value
-		}
+		toLog = append(toLog, String(fieldTraceDuration, time.Since(l.trace.startTime).String()))
 	}
-	toLog := make([]LogPair, 0, len(dedup))
-	for k, v := range dedup {
-		toLog = append(toLog, LogPair{key: k, value: v})
-	}
-	return toLog
+	toLog = append(toLog, pairs...)
+	return l.dupPolicy.dedup(toLog)
 }
 
+// logPairs should be called by the logging methods of Logger directly, the caller of them is logged.
 func (l Logger) logPairs(level Level, subject string, pairs []LogPair) {
-	if !(level >= l.level) {
+	if !l.enabled(level) {
 		return
 	}
-	toOutputs := l.marchLevelOutputs(level)
-	if len(toOutputs) == 0 {
+	l.logPairsWithCaller(callerPC(2), level, subject, pairs)
+}
+
+func (l Logger) logPairsWithCaller(pc uintptr, level Level, subject string, pairs []LogPair) {
+	if !l.enabled(level) {
 		return
 	}
-	toLog := l.producePairs(pairs)
-	for _, it := range toOutputs {
-		it.LogModuleAndPairs(level, subject, toLog)
+	pairs = expandErrors(pairs)
+	if l.errorStack && level >= LevelError && !hasKey(pairs, fieldStack) {
+		pairs = append(pairs[:len(pairs):len(pairs)], Stack(callerStack()))
+	}
+	subject = l.redactor.RedactSubject(subject)
+	toLog := l.redactor.Redact(l.producePairs(pairs))
+	for _, it := range l.outputs {
+		if level >= it.Level() {
+			logToOutput(it, pc, level, subject, toLog)
+		}
 	}
 }
 
@@ -141,6 +236,93 @@ func (l Logger) Error(subject string, pairs ...LogPair) {
 	l.logPairs(LevelError, subject, pairs)
 }
 
+// Fatal logs to all outputs, flushes them and then exits the process with status 1.
+func (l Logger) Fatal(subject string, pairs ...LogPair) {
+	l.logPairs(LevelFatal, subject, pairs)
+	l.Sync()
+	exit(1)
+}
+
+// 格式化的方法仅在可输出时才会格式化subject
+
+func (l Logger) Debugf(format string, args ...interface{}) {
+	if l.enabled(LevelDebug) {
+		l.logPairs(LevelDebug, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (l Logger) Infof(format string, args ...interface{}) {
+	if l.enabled(LevelInfo) {
+		l.logPairs(LevelInfo, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (l Logger) Warnf(format string, args ...interface{}) {
+	if l.enabled(LevelWarn) {
+		l.logPairs(LevelWarn, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (l Logger) Errorf(format string, args ...interface{}) {
+	if l.enabled(LevelError) {
+		l.logPairs(LevelError, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (l Logger) Fatalf(format string, args ...interface{}) {
+	if l.enabled(LevelFatal) {
+		l.logPairs(LevelFatal, fmt.Sprintf(format, args...), nil)
+	}
+	l.Sync()
+	exit(1)
+}
+
+func (l Logger) enabled(level Level) bool {
+	return level >= l.level.Level() && l.CanOutput(level)
+}
+
+var exit = os.Exit
+
+// Syncer is implemented by the Output which buffers logs.
+type Syncer interface {
+	Sync() error
+}
+
+// Sync flushes all outputs implementing Syncer, errors of them are joined.
+// It is safe to be called concurrently, e.g. from a signal handler.
+func (l Logger) Sync() error {
+	var errs []error
+	for _, it := range l.outputs {
+		if syncer, ok := it.(Syncer); ok {
+			if err := syncer.Sync(); err != nil {
+				errs = append(errs, outputError(it, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and then closes all outputs implementing io.Closer, errors of them are joined.
+// Outputs shared by other loggers are closed too, and they should not be used after.
+func (l Logger) Close() error {
+	errs := []error{l.Sync()}
+	for _, it := range l.outputs {
+		if closer, ok := it.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, outputError(it, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func outputError(output Output, err error) error {
+	if named, ok := output.(NamedOutput); ok && named.Name() != "" {
+		return fmt.Errorf("log: output %q: %w", named.Name(), err)
+	}
+	return fmt.Errorf("log: %w", err)
+}
+
 func (l Logger) CanOutput(level Level) bool {
 	for _, it := range l.outputs {
 		if level >= it.Level() {
@@ -152,44 +334,50 @@ func (l Logger) CanOutput(level Level) bool {
 
 func (l Logger) IsEmpty() bool { return len(l.outputs) == 0 }
 
+// LogPair is a key and its value, it's 40 bytes like slog.Attr to keep the variadic pairs small.
 type LogPair struct {
-	key   string
+	key string
+	// num stores the value of integer, float, bool, duration and time kinds,
+	// the length of string kinds, and the kind of the others.
+	num int64
+	// value stores the kind of integer, float, bool and duration kinds, the location of time kind,
+	// the data pointer of string kinds, and the value of the others.
 	value interface{}
 }
 
+func (p LogPair) Key() string { return p.key }
+
 func (p LogPair) String() string {
-	return fmt.Sprintf("%s: %v", p.key, p.value)
+	return fmt.Sprintf("%s: %v", p.key, p.Value())
 }
 
 func Any(k string, v interface{}) LogPair {
-	return LogPair{key: k, value: v}
+	return valuePair(k, pairKindAny, v)
 }
 
 func String(k, v string) LogPair {
-	return LogPair{key: k, value: v}
+	return stringPair(k, pairKindString, v)
 }
 
 func JsonString(k string, v any) LogPair {
 	vBytes, _ := json.Marshal(v)
-	return LogPair{key: k, value: string(vBytes)}
+	return stringPair(k, pairKindJSON, string(vBytes))
 }
 
-// func Number[T utility.Number](k string, v T) LogPair {
-// 	return LogPair{key: k, value: v}
-// }
-
+// Error logs err with the key error. If err wraps others, the chain is logged as error_causes,
+// and the pairs of the errors implementing ErrorFieldsProvider in the chain are logged too.
 func Error(err error) LogPair {
-	return LogPair{key: "error", value: err}
+	return valuePair("error", pairKindError, err)
 }
 
 func Stack(stack []byte) LogPair {
-	return LogPair{key: "stack", value: string(stack)}
+	return String(fieldStack, string(stack))
 }
 
 func ConvertStrMapToLogPairs(values map[string]interface{}) []LogPair {
 	pairs := make([]LogPair, 0, len(values))
 	for key, value := range values {
-		pairs = append(pairs, LogPair{key: key, value: value})
+		pairs = append(pairs, Any(key, value))
 	}
 	return pairs
 }
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"os"
 	"testing"
 	"time"
 
@@ -19,9 +21,90 @@ func TestLogger(t *testing.T) {
 	assert.Equal(t, 2, len0)
 	assert.Equal(t, 3, len1)
 
-	assert.Equal(t, 1, len(l0.marchLevelOutputs(LevelDebug)))
 	assert.Equal(t, len1+1, len(l1.producePairs(nil)))
 	assert.Equal(t, len1+2, len(l1.producePairs([]LogPair{Any("C", 1)})))
 
 	assert.Equal(t, len0, len(l0.trace.pairs))
 }
+
+func TestLoggerFormattedAndFatal(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(newZapLogger("", MakeLocalFormat(MessageFormatJSON), LevelInfo, newZapWriter(buf)))
+
+	l.Debugf("hidden %d", 1)
+	assert.Equal(t, 0, buf.Len())
+	l.Infof("shown %d", 1)
+	assert.Contains(t, buf.String(), `"msg":"shown 1"`)
+	assert.Contains(t, buf.String(), `"caller":"log/log_test.go:`)
+
+	code := 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+	buf.Reset()
+	l.Fatal("fatal", Any("a", 1))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, buf.String(), `"level":"fatal","ts"`)
+	assert.Contains(t, buf.String(), `"caller":"log/log_test.go:`)
+	buf.Reset()
+	l.Fatalf("fatal %s", "f")
+	assert.Contains(t, buf.String(), `"msg":"fatal f"`)
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder("rec", LevelInfo)
+	l := NewLogger(r).WithTraceLogs(String("user", "u1"))
+	l.Debug("hidden")
+	l.Info("login", Any("ok", true))
+	l.Warnf("retry %d", 2)
+
+	assert.Equal(t, []string{"login", "retry 2"}, r.Subjects())
+	r.AssertContains(t, "login", "user", "ok")
+	r.AssertNotContains(t, "hidden")
+	v, ok := r.FindBySubject("login")[0].Value("ok")
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+	assert.Equal(t, 1, len(r.FindByLevel(LevelWarn)))
+
+	mock := &mockT{}
+	assert.False(t, r.AssertContains(mock, "login", "missing"))
+	assert.False(t, r.AssertContains(mock, "logout"))
+	assert.Equal(t, 2, mock.errors)
+
+	r.Reset()
+	assert.Equal(t, 0, r.Len())
+}
+
+type mockT struct{ errors int }
+
+func (*mockT) Helper()                         {}
+func (m *mockT) Errorf(string, ...interface{}) { m.errors++ }
+
+func TestLoggerPairsOrderAndDuplicates(t *testing.T) {
+	r := NewRecorder("", LevelDebug)
+	l := NewLogger(r).WithTrace(NewTrace("T", time.Time{}, String("b", "1"), String("a", "2")))
+	l.Info("s", String("z", "3"), Any("a", 4), Any("a", 5))
+	last, _ := r.Last()
+	assert.Equal(t, []string{"b", "a", "trace_id", "z"}, last.Keys())
+	v, _ := last.Value("a")
+	assert.Equal(t, []interface{}{"2", 4, 5}, v)
+
+	cases := []struct {
+		policy DuplicatePolicy
+		pairs  []LogPair
+	}{
+		{DuplicateKeepFirst, []LogPair{String("b", "1"), String("a", "2"), String("trace_id", "T"), Any("c", 3)}},
+		{DuplicateKeepLast, []LogPair{String("b", "1"), Any("a", 5), String("trace_id", "T"), Any("c", 3)}},
+		{DuplicateSuffix, []LogPair{String("b", "1"), String("a", "2"), String("trace_id", "T"), Any("a_1", 4), Any("c", 3), Any("a_2", 5)}},
+	}
+	for _, it := range cases {
+		l.WithDuplicatePolicy(it.policy).Info("s", Any("a", 4), Any("c", 3), Any("a", 5))
+		last, _ = r.Last()
+		assert.Equal(t, it.pairs, last.Pairs, it.policy.String())
+	}
+
+	pairs := []LogPair{Any("a", 1), Any("a", 2)}
+	NewLogger(r).WithDuplicatePolicy(DuplicateKeepLast).Info("s", pairs...)
+	last, _ = r.Last()
+	assert.Equal(t, []LogPair{Any("a", 2)}, last.Pairs)
+	assert.Equal(t, []LogPair{Any("a", 1), Any("a", 2)}, pairs)
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtBufferPool = buffer.NewPool()
+
+// logfmtEncoder renders a record as logfmt:
+//
+//	ts=2024-01-02T15:04:05Z level=info logger=app caller=main.go:12 msg="user login" user=tom
+//
+// Objects and arrays are rendered as JSON and quoted.
+type logfmtEncoder struct {
+	*fieldEncoder
+	cfg zapcore.EncoderConfig
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return logfmtEncoder{fieldEncoder: &fieldEncoder{}, cfg: cfg}
+}
+
+func (e logfmtEncoder) Clone() zapcore.Encoder {
+	e.fieldEncoder = e.fieldEncoder.clone()
+	return e
+}
+
+func (e logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.fieldEncoder.clone()
+	for _, it := range fields {
+		it.AddTo(enc)
+	}
+
+	buf := logfmtBufferPool.Get()
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		appendLogfmt(buf, e.cfg.TimeKey, encodePrimitive(func(pe zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeTime(ent.Time, pe) }))
+	}
+	if e.cfg.LevelKey != "" && e.cfg.EncodeLevel != nil {
+		appendLogfmt(buf, e.cfg.LevelKey, encodePrimitive(func(pe zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeLevel(ent.Level, pe) }))
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		appendLogfmt(buf, e.cfg.NameKey, ent.LoggerName)
+	}
+	if e.cfg.CallerKey != "" && e.cfg.EncodeCaller != nil && ent.Caller.Defined {
+		appendLogfmt(buf, e.cfg.CallerKey, encodePrimitive(func(pe zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeCaller(ent.Caller, pe) }))
+	}
+	if e.cfg.MessageKey != "" {
+		appendLogfmt(buf, e.cfg.MessageKey, ent.Message)
+	}
+	for _, it := range enc.fields {
+		appendLogfmt(buf, it.key, it.value)
+	}
+	if e.cfg.StacktraceKey != "" && ent.Stack != "" {
+		appendLogfmt(buf, e.cfg.StacktraceKey, ent.Stack)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+func appendLogfmt(buf *buffer.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(logfmtKey(key))
+	buf.AppendByte('=')
+	if logfmtNeedsQuote(value) {
+		buf.AppendString(strconv.Quote(value))
+	} else {
+		buf.AppendString(value)
+	}
+}
+
+// logfmtKey replaces the characters not allowed in keys with '_'.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(newZapLogger("app", MakeLocalFormat(MessageFormatLogfmt), LevelDebug, newZapWriter(buf)))
+	l.Info("user login",
+		String("user", "tom"), String("note", `say "hi"`), String("empty", ""), Int("n", 2), Bool("ok", true),
+		String("multi", "a\nb"), String("a key", "x=y"), Strings("tags", []string{"a", "b"}))
+
+	assert.Regexp(t, regexp.MustCompile(`^ts=\S+ level=info logger=app caller=log/logfmt_encoder_test\.go:\d+ msg="user login" `+
+		`user=tom note="say \\"hi\\"" empty="" n=2 ok=true multi="a\\nb" a_key="x=y" tags="\[\\"a\\",\\"b\\"\]"\n$`), buf.String())
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	assert.Equal(t, "k_e_y", logfmtKey("k=e y"))
+	assert.Equal(t, "_", logfmtKey(""))
+	assert.True(t, logfmtNeedsQuote(`a\b`))
+	assert.False(t, logfmtNeedsQuote("héllo"))
+}
+
+func TestLogfmtEncoderKeys(t *testing.T) {
+	format := MakeLocalFormat(MessageFormatLogfmt)
+	format.TimeKey = ""
+	format.CallerKey = ""
+	enc := makeZapEncoder(format)
+	enc.AddString("base", "1")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "hi"}, []zapcore.Field{Int("n", 1).zapField("n")})
+	assert.Nil(t, err)
+	assert.Equal(t, "level=warn msg=hi base=1 n=1\n", buf.String())
+	// the fields of the clone are not shared
+	clone := enc.Clone()
+	clone.AddString("more", "2")
+	buf, _ = enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "hi"}, nil)
+	assert.Equal(t, "level=warn msg=hi base=1\n", buf.String())
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+
+	subjectHTTPRequest = "http request"
+)
+
+// HTTPMiddleware installs a traced Logger into the context of every request,
+// and logs a line with status, bytes and trace_dur when the request is completed.
+//
+// The trace is continued from the traceparent header, or else it is started with the X-Request-ID header,
+// or a generated trace-id. The traceparent of the request span is set to the response.
+func HTTPMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			pairs := []LogPair{
+				String("method", r.Method),
+				String("path", r.URL.Path),
+				String("remote_addr", r.RemoteAddr),
+			}
+			var trace Trace
+			if parent, err := SpanContextFromHeader(r.Header); err == nil {
+				trace = NewTraceWithSpanContext(parent, start).StartSpan(start, pairs...)
+			} else if requestID := r.Header.Get(HeaderRequestID); requestID != "" {
+				trace = NewTrace(requestID, start, pairs...)
+			} else {
+				trace = NewTraceWithSpanContext(NewSpanContext(), start, pairs...)
+			}
+			reqLogger := logger.WithTrace(trace)
+			trace.span.InjectHeader(w.Header())
+
+			rw := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rw, r.WithContext(reqLogger.WrapContext(r.Context())))
+
+			pairs = []LogPair{Int("status", rw.statusCode()), Int64("bytes", rw.bytes)}
+			if rw.statusCode() >= http.StatusInternalServerError {
+				reqLogger.Error(subjectHTTPRequest, pairs...)
+			} else {
+				reqLogger.Info(subjectHTTPRequest, pairs...)
+			}
+		})
+	}
+}
+
+// responseRecorder records status and bytes written to the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseRecorder) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the original ResponseWriter.
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("log: hijack is not supported")
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	r := NewRecorder("", LevelDebug)
+	handler := HTTPMiddleware(NewLogger(r))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WithContext(req.Context()).Info("handling")
+		if req.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, []string{"handling", subjectHTTPRequest}, r.Subjects())
+	r.AssertContains(t, "handling", "method", "path", "remote_addr", "trace_id", "span_id", "parent_span_id")
+	done, _ := r.Last()
+	assert.Equal(t, LevelInfo, done.Level)
+	for key, value := range map[string]interface{}{
+		"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"parent_span_id": "00f067aa0ba902b7",
+		"path":           "/ok",
+		"status":         int64(200),
+		"bytes":          int64(5),
+	} {
+		v, _ := done.Value(key)
+		assert.Equal(t, value, v, key)
+	}
+	assert.True(t, done.Has("trace_dur"))
+	sc, err := SpanContextFromHeader(w.Header())
+	assert.Nil(t, err)
+	spanID, _ := done.Value("span_id")
+	assert.Equal(t, spanID, sc.SpanID.String())
+
+	// the completion line is logged by the middleware
+	buf := &bytes.Buffer{}
+	notFound := HTTPMiddleware(NewLogger(MakeWriterOutput("", MakeLocalFormat(MessageFormatLogfmt), LevelInfo, buf)))(http.NotFoundHandler())
+	notFound.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Regexp(t, regexp.MustCompile(`caller=log/middleware\.go:\d+ msg="http request"`), buf.String())
+
+	r.Reset()
+	req = httptest.NewRequest(http.MethodPost, "/fail", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	done, _ = r.Last()
+	assert.Equal(t, LevelError, done.Level)
+	v, _ := done.Value("trace_id")
+	assert.Equal(t, "req-1", v)
+	v, _ = done.Value("status")
+	assert.Equal(t, int64(http.StatusBadGateway), v)
+
+	r.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	r.AssertContains(t, subjectHTTPRequest, "trace_id", "span_id")
+}
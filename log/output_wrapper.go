@@ -0,0 +1,55 @@
+package log
+
+import "io"
+
+// outputWrapper forwards the optional interfaces to the wrapped Output,
+// it is embedded by the outputs decorating another one.
+type outputWrapper struct {
+	Output
+}
+
+func (w outputWrapper) Name() string {
+	if named, ok := w.Output.(NamedOutput); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+func (w outputWrapper) SetLevel(level Level) {
+	if setter, ok := w.Output.(LevelSetter); ok {
+		setter.SetLevel(level)
+	}
+}
+
+func (w outputWrapper) canSetLevel() bool { return canSetLevel(w.Output) }
+
+func (w outputWrapper) Sync() error {
+	if syncer, ok := w.Output.(Syncer); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+func (w outputWrapper) Close() error {
+	if closer, ok := w.Output.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// levelSetterWrapper is implemented by the outputs forwarding SetLevel to others,
+// which change nothing if none of the others is a LevelSetter.
+type levelSetterWrapper interface {
+	canSetLevel() bool
+}
+
+// canSetLevel reports whether SetLevel of output changes the level of it.
+func canSetLevel(output Output) bool {
+	if _, ok := output.(LevelSetter); !ok {
+		return false
+	}
+	if w, ok := output.(levelSetterWrapper); ok {
+		return w.canSetLevel()
+	}
+	return true
+}
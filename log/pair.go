@@ -0,0 +1,249 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type pairKind uint8
+
+const (
+	pairKindAny pairKind = iota
+	pairKindString
+	pairKindJSON
+	pairKindError
+	pairKindInt64
+	pairKindUint64
+	pairKindFloat64
+	pairKindBool
+	pairKindDuration
+	pairKindTime
+	pairKindBytes
+	pairKindStringer
+	pairKindStrings
+	pairKindObject
+	pairKindArray
+)
+
+type (
+	// timeLocation is the location of time kind, distinguishing it from the other kinds in LogPair.value.
+	timeLocation time.Location
+	// stringData and jsonData are the data pointers of string and JSON kinds.
+	stringData *byte
+	jsonData   *byte
+)
+
+func numPair(k string, kind pairKind, num int64) LogPair {
+	return LogPair{key: k, num: num, value: kind}
+}
+
+func stringPair(k string, kind pairKind, v string) LogPair {
+	p := LogPair{key: k, num: int64(len(v))}
+	if kind == pairKindJSON {
+		p.value = jsonData(unsafe.StringData(v))
+	} else {
+		p.value = stringData(unsafe.StringData(v))
+	}
+	return p
+}
+
+func valuePair(k string, kind pairKind, v interface{}) LogPair {
+	return LogPair{key: k, num: int64(kind), value: v}
+}
+
+func (p LogPair) kind() pairKind {
+	switch v := p.value.(type) {
+	case pairKind:
+		return v
+	case *timeLocation:
+		return pairKindTime
+	case stringData:
+		return pairKindString
+	case jsonData:
+		return pairKindJSON
+	default:
+		return pairKind(p.num)
+	}
+}
+
+// str returns the value of string and JSON kinds.
+func (p LogPair) str() string {
+	switch v := p.value.(type) {
+	case stringData:
+		return unsafe.String((*byte)(v), p.num)
+	case jsonData:
+		return unsafe.String((*byte)(v), p.num)
+	default:
+		return ""
+	}
+}
+
+// ObjectMarshaler and ArrayMarshaler let a type log itself as a structured object or array
+// without reflection.
+type (
+	ObjectMarshaler = zapcore.ObjectMarshaler
+	ArrayMarshaler  = zapcore.ArrayMarshaler
+)
+
+func Int(k string, v int) LogPair {
+	return Int64(k, int64(v))
+}
+
+func Int64(k string, v int64) LogPair {
+	return numPair(k, pairKindInt64, v)
+}
+
+func Uint(k string, v uint) LogPair {
+	return Uint64(k, uint64(v))
+}
+
+func Uint64(k string, v uint64) LogPair {
+	return numPair(k, pairKindUint64, int64(v))
+}
+
+func Float64(k string, v float64) LogPair {
+	return numPair(k, pairKindFloat64, int64(math.Float64bits(v)))
+}
+
+func Bool(k string, v bool) LogPair {
+	if v {
+		return numPair(k, pairKindBool, 1)
+	}
+	return numPair(k, pairKindBool, 0)
+}
+
+func Duration(k string, v time.Duration) LogPair {
+	return numPair(k, pairKindDuration, int64(v))
+}
+
+// Time keeps the location of v, the time out of range of UnixNano is stored as Any.
+func Time(k string, v time.Time) LogPair {
+	if v.Before(minTimeInt64) || v.After(maxTimeInt64) {
+		return Any(k, v)
+	}
+	return LogPair{key: k, num: v.UnixNano(), value: (*timeLocation)(v.Location())}
+}
+
+var (
+	minTimeInt64 = time.Unix(0, math.MinInt64)
+	maxTimeInt64 = time.Unix(0, math.MaxInt64)
+)
+
+// Bytes logs v as base64 like Any does for []byte.
+func Bytes(k string, v []byte) LogPair {
+	return valuePair(k, pairKindBytes, v)
+}
+
+// Stringer calls v.String() only when the pair is written.
+func Stringer(k string, v fmt.Stringer) LogPair {
+	return valuePair(k, pairKindStringer, v)
+}
+
+func Strings(k string, v []string) LogPair {
+	return valuePair(k, pairKindStrings, v)
+}
+
+func Object(k string, v ObjectMarshaler) LogPair {
+	return valuePair(k, pairKindObject, v)
+}
+
+func Array(k string, v ArrayMarshaler) LogPair {
+	return valuePair(k, pairKindArray, v)
+}
+
+// Value returns the value of p as the type passed to its constructor,
+// except that the values of Int and Uint are returned as int64 and uint64.
+func (p LogPair) Value() interface{} {
+	switch p.kind() {
+	case pairKindString, pairKindJSON:
+		return p.str()
+	case pairKindInt64:
+		return p.num
+	case pairKindUint64:
+		return uint64(p.num)
+	case pairKindFloat64:
+		return math.Float64frombits(uint64(p.num))
+	case pairKindBool:
+		return p.num == 1
+	case pairKindDuration:
+		return time.Duration(p.num)
+	case pairKindTime:
+		return p.time()
+	default:
+		return p.value
+	}
+}
+
+func (p LogPair) time() time.Time {
+	t := time.Unix(0, p.num)
+	if loc, ok := p.value.(*timeLocation); ok && loc != nil {
+		return t.In((*time.Location)(loc))
+	}
+	return t
+}
+
+// zapField converts p to the typed zap field named key.
+func (p LogPair) zapField(key string) zap.Field {
+	switch p.kind() {
+	case pairKindString, pairKindJSON:
+		return zap.String(key, p.str())
+	case pairKindError:
+		if err, ok := p.value.(error); ok {
+			return zap.NamedError(key, err)
+		}
+	case pairKindInt64:
+		return zap.Int64(key, p.num)
+	case pairKindUint64:
+		return zap.Uint64(key, uint64(p.num))
+	case pairKindFloat64:
+		return zap.Float64(key, math.Float64frombits(uint64(p.num)))
+	case pairKindBool:
+		return zap.Bool(key, p.num == 1)
+	case pairKindDuration:
+		return zap.Duration(key, time.Duration(p.num))
+	case pairKindTime:
+		return zap.Time(key, p.time())
+	case pairKindBytes:
+		return zap.Binary(key, p.value.([]byte))
+	case pairKindStringer:
+		if v, ok := p.value.(fmt.Stringer); ok {
+			return zap.Stringer(key, v)
+		}
+	case pairKindStrings:
+		return zap.Strings(key, p.value.([]string))
+	case pairKindObject:
+		if v, ok := p.value.(ObjectMarshaler); ok {
+			return zap.Object(key, v)
+		}
+	case pairKindArray:
+		if v, ok := p.value.(ArrayMarshaler); ok {
+			return zap.Array(key, v)
+		}
+	}
+	// Any and the nil values of Error, Stringer, Object and Array
+	return zap.Any(key, p.value)
+}
+
+// stringOf calls String of the Stringer v, nil and nil pointers are "<nil>" like zap.Stringer.
+func stringOf(v interface{}) (s string) {
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return "<nil>"
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				s = "<nil>"
+				return
+			}
+			panic(err)
+		}
+	}()
+	return stringer.String()
+}
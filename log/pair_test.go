@@ -0,0 +1,181 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type pairUser struct {
+	name string
+	age  int
+}
+
+func (u pairUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.name)
+	enc.AddInt("age", u.age)
+	return nil
+}
+
+func TestTypedPairs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(newZapLogger("", MakeLocalFormat(MessageFormatJSON), LevelDebug, newZapWriter(buf)))
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	pairs := []LogPair{
+		Int("int", -1),
+		Int64("int64", 1<<40),
+		Uint("uint", 2),
+		Uint64("uint64", 1<<63),
+		Float64("float", 1.5),
+		Bool("bool", true),
+		Duration("dur", time.Second),
+		Time("time", at),
+		Bytes("bytes", []byte("hi")),
+		Stringer("stringer", time.Minute),
+		Strings("strings", []string{"a", "b"}),
+		Object("user", pairUser{name: "u", age: 3}),
+		Error(errors.New("failed")),
+		String("msg", "renamed"),
+	}
+	l.Info("typed", pairs...)
+
+	var logged map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &logged))
+	assert.Equal(t, -1.0, logged["int"])
+	assert.Equal(t, float64(1<<40), logged["int64"])
+	assert.Equal(t, 2.0, logged["uint"])
+	assert.Equal(t, float64(1<<63), logged["uint64"])
+	assert.Equal(t, 1.5, logged["float"])
+	assert.Equal(t, true, logged["bool"])
+	assert.Equal(t, 1.0, logged["dur"])
+	assert.Equal(t, "2024-05-01T08:00:00+08:00", logged["time"])
+	assert.Equal(t, "aGk=", logged["bytes"])
+	assert.Equal(t, "1m0s", logged["stringer"])
+	assert.Equal(t, []interface{}{"a", "b"}, logged["strings"])
+	assert.Equal(t, map[string]interface{}{"name": "u", "age": 3.0}, logged["user"])
+	assert.Equal(t, "failed", logged["error"])
+	assert.Equal(t, "renamed", logged["_msg"])
+
+	assert.Equal(t, int64(-1), pairs[0].Value())
+	assert.Equal(t, uint64(1<<63), pairs[3].Value())
+	assert.Equal(t, 1.5, pairs[4].Value())
+	assert.Equal(t, true, pairs[5].Value())
+	assert.Equal(t, time.Second, pairs[6].Value())
+	assert.True(t, at.Equal(pairs[7].Value().(time.Time)))
+	assert.Equal(t, at.Location(), pairs[7].Value().(time.Time).Location())
+	assert.Equal(t, "bool: true", pairs[5].String())
+	assert.Equal(t, "renamed", pairs[13].Value())
+	assert.Equal(t, "", String("empty", "").Value())
+	assert.Equal(t, `{"a":1}`, JsonString("json", map[string]int{"a": 1}).Value())
+	assert.Equal(t, unsafe.Sizeof(slog.Attr{}), unsafe.Sizeof(LogPair{}))
+}
+
+func TestNilPairs(t *testing.T) {
+	var nilPointer *pairPointer
+	pairs := []LogPair{
+		Stringer("stringer", nil),
+		Stringer("nil_pointer", nilPointer),
+		Object("object", nil),
+		Array("array", nil),
+		Error(nil),
+	}
+
+	buf := &bytes.Buffer{}
+	l := NewLogger(newZapLogger("", MakeLocalFormat(MessageFormatJSON), LevelDebug, newZapWriter(buf)))
+	l.Info("nil", pairs...)
+	var logged map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &logged))
+	for _, key := range []string{"stringer", "object", "array", "error"} {
+		v, ok := logged[key]
+		assert.True(t, ok, key)
+		assert.Nil(t, v, key)
+	}
+	assert.Equal(t, "<nil>", logged["nil_pointer"])
+
+	buf.Reset()
+	slogger := NewLogger(MakeSlogOutput("", LevelDebug, slog.NewJSONHandler(buf, nil)))
+	slogger.Info("nil", pairs...)
+	assert.Contains(t, buf.String(), `"nil_pointer":"<nil>"`)
+
+	redactor, err := NewRedactor(RedactConfig{Rules: []RedactRule{{ValuePatterns: []string{"secret"}}}})
+	assert.Nil(t, err)
+	r := NewRecorder("", LevelDebug)
+	NewLogger(r).WithRedactor(redactor).Info("nil", pairs...)
+	record, _ := r.Last()
+	assert.Equal(t, len(pairs), len(record.Pairs))
+}
+
+type pairPointer struct{ s string }
+
+func (p pairPointer) String() string { return p.s }
+
+func benchmarkLogger() Logger {
+	return NewLogger(newZapLogger("", MakeLocalFormat(MessageFormatJSON), LevelDebug, newZapWriter(io.Discard)))
+}
+
+// sugaredOutput is the way zapOutput logged before typed pairs:
+// boxed values as key-value arguments of zap's sugared logger.
+type sugaredOutput struct {
+	zapOutput
+}
+
+func (o sugaredOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	argPairs := make([]interface{}, 0, len(pairs)*2)
+	for i := range pairs {
+		argPairs = append(argPairs, pairs[i].key, pairs[i].Value())
+	}
+	o.output.Infow(subject, argPairs...)
+}
+
+func BenchmarkSugaredAnyPairs(b *testing.B) {
+	l := NewLogger(sugaredOutput{benchmarkLogger().outputs[0].(zapOutput)})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		l.Info("bench",
+			Any("int", n),
+			Any("float", 1.5),
+			Any("bool", true),
+			Any("dur", time.Second),
+			Any("name", "value"),
+		)
+	}
+}
+
+func BenchmarkAnyPairs(b *testing.B) {
+	l := benchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		l.Info("bench",
+			Any("int", n),
+			Any("float", 1.5),
+			Any("bool", true),
+			Any("dur", time.Second),
+			Any("name", "value"),
+		)
+	}
+}
+
+func BenchmarkTypedPairs(b *testing.B) {
+	l := benchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		l.Info("bench",
+			Int("int", n),
+			Float64("float", 1.5),
+			Bool("bool", true),
+			Duration("dur", time.Second),
+			String("name", "value"),
+		)
+	}
+}
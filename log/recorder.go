@@ -0,0 +1,183 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a log record captured by Recorder.
+type Record struct {
+	Level   Level
+	Subject string
+	// Pairs are in the order they were passed to the Output.
+	Pairs []LogPair
+	Time  time.Time
+}
+
+// Value returns the value of the first pair with key.
+func (r Record) Value(key string) (interface{}, bool) {
+	for _, it := range r.Pairs {
+		if it.key == key {
+			return it.Value(), true
+		}
+	}
+	return nil, false
+}
+
+func (r Record) Has(key string) bool {
+	_, ok := r.Value(key)
+	return ok
+}
+
+// Keys returns keys of the pairs in order.
+func (r Record) Keys() []string {
+	keys := make([]string, 0, len(r.Pairs))
+	for _, it := range r.Pairs {
+		keys = append(keys, it.key)
+	}
+	return keys
+}
+
+// TestingT is the subset of testing.TB used by Recorder's assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+var (
+	_ Output      = (*Recorder)(nil)
+	_ LevelSetter = (*Recorder)(nil)
+	_ NamedOutput = (*Recorder)(nil)
+)
+
+// Recorder is an Output keeping all records in memory, it's used to test what a Logger emits.
+type Recorder struct {
+	name  string
+	level *LevelVar
+
+	mu      sync.Mutex
+	records []Record
+}
+
+func NewRecorder(name string, level Level) *Recorder {
+	return &Recorder{name: name, level: NewLevelVar(level)}
+}
+
+func (r *Recorder) Name() string { return r.name }
+
+func (r *Recorder) Level() Level { return r.level.Level() }
+
+func (r *Recorder) SetLevel(level Level) { r.level.Set(level) }
+
+func (r *Recorder) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	record := Record{
+		Level:   l,
+		Subject: subject,
+		Pairs:   append([]LogPair(nil), pairs...),
+		Time:    time.Now(),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+}
+
+// Records returns a copy of all records.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+// Last returns the latest record.
+func (r *Recorder) Last() (Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) == 0 {
+		return Record{}, false
+	}
+	return r.records[len(r.records)-1], true
+}
+
+func (r *Recorder) Filter(match func(Record) bool) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []Record
+	for _, it := range r.records {
+		if match(it) {
+			records = append(records, it)
+		}
+	}
+	return records
+}
+
+func (r *Recorder) FindBySubject(subject string) []Record {
+	return r.Filter(func(it Record) bool { return it.Subject == subject })
+}
+
+func (r *Recorder) FindBySubjectPrefix(prefix string) []Record {
+	return r.Filter(func(it Record) bool { return strings.HasPrefix(it.Subject, prefix) })
+}
+
+func (r *Recorder) FindByLevel(level Level) []Record {
+	return r.Filter(func(it Record) bool { return it.Level == level })
+}
+
+// Subjects returns subjects of all records in order.
+func (r *Recorder) Subjects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subjects := make([]string, 0, len(r.records))
+	for _, it := range r.records {
+		subjects = append(subjects, it.Subject)
+	}
+	return subjects
+}
+
+// AssertContains reports an error to t unless a record with subject has all the keys.
+func (r *Recorder) AssertContains(t TestingT, subject string, keys ...string) bool {
+	t.Helper()
+	records := r.FindBySubject(subject)
+	if len(records) == 0 {
+		t.Errorf("log: no record with subject %q, got %q", subject, r.Subjects())
+		return false
+	}
+	for _, record := range records {
+		if record.hasKeys(keys) {
+			return true
+		}
+	}
+	t.Errorf("log: no record with subject %q contains keys %q, got %q", subject, keys, records[0].Keys())
+	return false
+}
+
+// AssertNotContains reports an error to t if any record has subject.
+func (r *Recorder) AssertNotContains(t TestingT, subject string) bool {
+	t.Helper()
+	if n := len(r.FindBySubject(subject)); n > 0 {
+		t.Errorf("log: %d records with subject %q", n, subject)
+		return false
+	}
+	return true
+}
+
+func (r Record) hasKeys(keys []string) bool {
+	for _, key := range keys {
+		if !r.Has(key) {
+			return false
+		}
+	}
+	return true
+}
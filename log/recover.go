@@ -0,0 +1,78 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+const (
+	fieldPanic   = "panic"
+	subjectPanic = "panic recovered"
+)
+
+// Recover recovers the panic and logs it with the stack at LevelError, it should be deferred directly:
+//
+//	defer log.Recover(logger)
+//
+// The panic is not propagated.
+func Recover(logger Logger) {
+	if r := recover(); r != nil {
+		logger.logPanic(r)
+	}
+}
+
+// Go runs fn in a new goroutine, the panic of fn is recovered and logged by logger.
+func Go(logger Logger, fn func()) {
+	go func() {
+		defer Recover(logger)
+		fn()
+	}()
+}
+
+// RecoverMiddleware recovers the panics of handlers and responds 500 if nothing is written yet.
+// The panics are logged by the Logger of the request context, so it should be installed inside HTTPMiddleware,
+// or else by logger.
+// http.ErrAbortHandler is propagated to abort the response like net/http.
+func RecoverMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				FromContextOr(r.Context(), logger).logPanic(rec)
+				if rw.status == 0 {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func (l Logger) logPanic(r interface{}) {
+	pair := String(fieldPanic, fmt.Sprint(r))
+	if err, ok := r.(error); ok {
+		pair = valuePair(fieldPanic, pairKindError, err)
+	}
+	l.logPairsWithCaller(panicPC(), LevelError, subjectPanic, []LogPair{pair, Stack(callerStack())})
+}
+
+// panicPC returns the program counter of the first frame outside this package, slog and runtime,
+// which is the panicking function when it's called by a deferred recovery.
+func panicPC() uintptr {
+	pcs := make([]uintptr, 32)
+	for _, pc := range pcs[:runtime.Callers(2, pcs)] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !isInternalFrame(frame.File, frame.Function) {
+			return pc
+		}
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicking(v interface{}) {
+	panic(v)
+}
+
+func TestRecover(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(MakeWriterOutput("", MakeLocalFormat(MessageFormatLogfmt), LevelDebug, buf)).
+		WithTraceLogs(String("trace_id", "abc"))
+	func() {
+		defer Recover(l)
+		panicking("boom")
+	}()
+
+	line, stack, _ := strings.Cut(buf.String(), " stack=")
+	// the caller is the panicking function
+	assert.Regexp(t, regexp.MustCompile(`level=error caller=log/recover_test\.go:\d+ msg="panic recovered" trace_id=abc panic=boom$`), line)
+	assert.True(t, strings.HasPrefix(stack, `"github.com/byte-power/go-utility/log.panicking\n\t`))
+	assert.Contains(t, stack, "log.TestRecover")
+	assert.NotContains(t, stack, "runtime/panic.go")
+
+	// errors are logged with their causes
+	r := NewRecorder("r", LevelDebug)
+	func() {
+		defer Recover(NewLogger(r))
+		panicking(errors.Join(errors.New("a"), errors.New("b")))
+	}()
+	record, _ := r.Last()
+	assert.Equal(t, []string{"panic", "stack", "panic_causes"}, record.Keys())
+}
+
+func TestGo(t *testing.T) {
+	r := NewRecorder("r", LevelDebug)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	Go(NewLogger(r), func() {
+		defer wg.Done()
+		panicking("in goroutine")
+	})
+	wg.Wait()
+	assert.Eventually(t, func() bool { return r.Len() == 1 }, time.Second, time.Millisecond)
+	record, _ := r.Last()
+	assert.Equal(t, LevelError, record.Level)
+	v, _ := record.Value("panic")
+	assert.Equal(t, "in goroutine", v)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	r := NewRecorder("r", LevelDebug)
+	logger := NewLogger(r)
+	handler := HTTPMiddleware(logger)(RecoverMiddleware(Logger{})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/abort" {
+			panic(http.ErrAbortHandler)
+		}
+		panicking("handler failed")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	panics := r.FindBySubject(subjectPanic)
+	assert.Equal(t, 1, len(panics))
+	traceID, _ := panics[0].Value("trace_id")
+	assert.Equal(t, "req-1", traceID)
+	requests := r.FindBySubject(subjectHTTPRequest)
+	assert.Equal(t, 1, len(requests))
+	status, _ := requests[0].Value("status")
+	assert.Equal(t, int64(500), status)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+}
@@ -0,0 +1,437 @@
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/byte-power/go-utility/crypto"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactStrategy decides how a sensitive value is redacted.
+type RedactStrategy int
+
+const (
+	// RedactMask replaces the value with RedactConfig.Mask.
+	RedactMask RedactStrategy = iota
+	// RedactHash replaces the value with the prefix of its HMAC-SHA256 keyed by RedactConfig.HashKey,
+	// so the same values can still be correlated, but not be brute forced without the key.
+	RedactHash
+	// RedactDrop removes the pair, or the field of the nested map.
+	RedactDrop
+)
+
+const defaultRedactMask = "******"
+
+func (s RedactStrategy) MarshalText() ([]byte, error) {
+	switch s {
+	case RedactMask:
+		return []byte("mask"), nil
+	case RedactHash:
+		return []byte("hash"), nil
+	case RedactDrop:
+		return []byte("drop"), nil
+	default:
+		return nil, fmt.Errorf("log: unknown redact strategy %d", s)
+	}
+}
+
+func (s *RedactStrategy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "", "mask":
+		*s = RedactMask
+	case "hash":
+		*s = RedactHash
+	case "drop":
+		*s = RedactDrop
+	default:
+		return fmt.Errorf("log: unknown redact strategy %q", text)
+	}
+	return nil
+}
+
+// RedactRule matches sensitive values by key names, key globs and value regexes.
+// Keys and globs are case-insensitive, and they match the keys of nested maps and JSON too.
+// Values matching the regexes are redacted in part, or the whole pair is removed for RedactDrop,
+// the subject is redacted by the regexes too, and the parts matched by RedactDrop are masked.
+type RedactRule struct {
+	Keys          []string       `yaml:"keys" json:"keys"`
+	KeyGlobs      []string       `yaml:"key_globs" json:"key_globs"`
+	ValuePatterns []string       `yaml:"value_patterns" json:"value_patterns"`
+	Strategy      RedactStrategy `yaml:"strategy" json:"strategy"`
+}
+
+type RedactConfig struct {
+	Rules []RedactRule `yaml:"rules" json:"rules"`
+	// Mask is used by RedactMask, default is "******".
+	Mask string `yaml:"mask" json:"mask"`
+	// HashKey is the key of RedactHash, it's required if any rule uses RedactHash.
+	HashKey []byte `yaml:"-" json:"-"`
+	// HashKeyFile is the file of HashKey, used if HashKey is empty.
+	HashKeyFile string `yaml:"hash_key_file" json:"hash_key_file"`
+}
+
+func (cfg RedactConfig) hashKey() ([]byte, error) {
+	if len(cfg.HashKey) > 0 || cfg.HashKeyFile == "" {
+		return cfg.HashKey, nil
+	}
+	key, err := os.ReadFile(cfg.HashKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(key), nil
+}
+
+type redactRule struct {
+	keys     map[string]bool
+	globs    []string
+	patterns []*regexp.Regexp
+	strategy RedactStrategy
+}
+
+// Redactor removes sensitive values from pairs, it's safe for concurrent use.
+type Redactor struct {
+	rules   []redactRule
+	mask    string
+	hashKey []byte
+}
+
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	hashKey, err := cfg.hashKey()
+	if err != nil {
+		return nil, err
+	}
+	r := &Redactor{mask: cfg.Mask, hashKey: hashKey}
+	if r.mask == "" {
+		r.mask = defaultRedactMask
+	}
+	for i, it := range cfg.Rules {
+		if it.Strategy == RedactHash && len(hashKey) == 0 {
+			return nil, fmt.Errorf("log: redact rules[%d]: hash_key is required by hash strategy", i)
+		}
+		rule := redactRule{keys: make(map[string]bool, len(it.Keys)), strategy: it.Strategy}
+		for _, key := range it.Keys {
+			rule.keys[strings.ToLower(key)] = true
+		}
+		for _, glob := range it.KeyGlobs {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("log: redact rules[%d]: invalid key glob %q: %w", i, glob, err)
+			}
+			rule.globs = append(rule.globs, strings.ToLower(glob))
+		}
+		for _, pattern := range it.ValuePatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("log: redact rules[%d]: %w", i, err)
+			}
+			rule.patterns = append(rule.patterns, re)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// RedactSubject replaces the parts of subject matching the value patterns,
+// e.g. the values formatted by Infof, the parts matched by RedactDrop are masked.
+func (r *Redactor) RedactSubject(subject string) string {
+	if r == nil || len(r.rules) == 0 {
+		return subject
+	}
+	s, _ := r.redactMatches(subject, true)
+	return s
+}
+
+// Redact returns the redacted copy of pairs, pairs itself is not changed.
+func (r *Redactor) Redact(pairs []LogPair) []LogPair {
+	if r == nil || len(r.rules) == 0 {
+		return pairs
+	}
+	var toLog []LogPair
+	for i, it := range pairs {
+		redacted, keep, changed := r.redactPair(it)
+		if changed && toLog == nil {
+			toLog = make([]LogPair, i, len(pairs))
+			copy(toLog, pairs[:i])
+		}
+		if toLog != nil && keep {
+			toLog = append(toLog, redacted)
+		}
+	}
+	if toLog == nil {
+		return pairs
+	}
+	return toLog
+}
+
+func (r *Redactor) redactPair(p LogPair) (redacted LogPair, keep, changed bool) {
+	if rule := r.keyRule(p.key); rule != nil {
+		if rule.strategy == RedactDrop {
+			return p, false, true
+		}
+		return String(p.key, r.replace(rule.strategy, fmt.Sprint(p.Value()))), true, true
+	}
+	switch p.kind() {
+	case pairKindString:
+		s, drop := r.redactString(p.str())
+		return String(p.key, s), !drop, drop || s != p.str()
+	case pairKindJSON:
+		var v interface{}
+		if json.Unmarshal([]byte(p.str()), &v) != nil {
+			s, drop := r.redactString(p.str())
+			return stringPair(p.key, pairKindJSON, s), !drop, drop || s != p.str()
+		}
+		v, drop, changed := r.redactValue(v)
+		if !changed {
+			return p, true, false
+		}
+		vBytes, _ := json.Marshal(v)
+		return stringPair(p.key, pairKindJSON, string(vBytes)), !drop, true
+	case pairKindError:
+		err, ok := p.value.(error)
+		if !ok {
+			return p, true, false
+		}
+		msg := err.Error()
+		s, drop := r.redactString(msg)
+		if !drop && s == msg {
+			return p, true, false
+		}
+		return String(p.key, s), !drop, true
+	case pairKindStringer:
+		s, drop := r.redactString(stringOf(p.value))
+		return String(p.key, s), !drop, true
+	case pairKindStrings:
+		v, drop, changed := r.redactValue(p.value)
+		if !changed {
+			return p, true, false
+		}
+		return Strings(p.key, v.([]string)), !drop, true
+	case pairKindObject:
+		marshaler, ok := p.value.(ObjectMarshaler)
+		if !ok {
+			return p, true, false
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		marshaler.MarshalLogObject(enc)
+		v, drop, changed := r.redactValue(enc.Fields)
+		if !changed {
+			return p, true, false
+		}
+		return Any(p.key, v), !drop, true
+	case pairKindArray:
+		marshaler, ok := p.value.(ArrayMarshaler)
+		if !ok {
+			return p, true, false
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		enc.AddArray(p.key, marshaler)
+		v, drop, changed := r.redactValue(enc.Fields[p.key])
+		if !changed {
+			return p, true, false
+		}
+		return Any(p.key, v), !drop, true
+	case pairKindInt64, pairKindUint64, pairKindFloat64:
+		v, drop, changed := r.redactValue(p.Value())
+		if !changed {
+			return p, true, false
+		}
+		return String(p.key, v.(string)), !drop, true
+	case pairKindAny:
+		v, drop, changed := r.redactValue(p.value)
+		if !changed {
+			return p, true, false
+		}
+		return Any(p.key, v), !drop, true
+	default:
+		return p, true, false
+	}
+}
+
+// redactValue walks into strings, numbers, maps and slices,
+// the values of other types are returned as they are.
+func (r *Redactor) redactValue(v interface{}) (redacted interface{}, drop, changed bool) {
+	switch val := v.(type) {
+	case string:
+		s, drop := r.redactString(val)
+		return s, drop, drop || s != val
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, it := range val {
+			if rule := r.keyRule(k); rule != nil {
+				changed = true
+				if rule.strategy != RedactDrop {
+					m[k] = r.replace(rule.strategy, fmt.Sprint(it))
+				}
+				continue
+			}
+			redacted, drop, itChanged := r.redactValue(it)
+			changed = changed || itChanged
+			if !drop {
+				m[k] = redacted
+			}
+		}
+		if !changed {
+			return v, false, false
+		}
+		return m, false, true
+	case []interface{}:
+		s := make([]interface{}, 0, len(val))
+		for _, it := range val {
+			redacted, drop, itChanged := r.redactValue(it)
+			changed = changed || itChanged
+			if !drop {
+				s = append(s, redacted)
+			}
+		}
+		if !changed {
+			return v, false, false
+		}
+		return s, false, true
+	case []string:
+		s := make([]string, 0, len(val))
+		for _, it := range val {
+			redacted, drop := r.redactString(it)
+			changed = changed || drop || redacted != it
+			if !drop {
+				s = append(s, redacted)
+			}
+		}
+		if !changed {
+			return v, false, false
+		}
+		return s, false, true
+	default:
+		if s, ok := numberString(v); ok {
+			// numbers are replaced only if value patterns match, e.g. phone numbers logged as integers
+			redacted, drop := r.redactString(s)
+			if !drop && redacted == s {
+				return v, false, false
+			}
+			return redacted, drop, true
+		}
+		return r.redactComposite(v)
+	}
+}
+
+// numberString formats the integers and floats for value patterns.
+func numberString(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// redactComposite redacts the maps, slices and structs of other types by a JSON round-trip,
+// e.g. http.Header, the redacted value is the decoded JSON.
+func (r *Redactor) redactComposite(v interface{}) (redacted interface{}, drop, changed bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Array:
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v, false, false
+		}
+	default:
+		return v, false, false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, false, false
+	}
+	var decoded interface{}
+	if json.Unmarshal(data, &decoded) != nil {
+		return v, false, false
+	}
+	if redacted, drop, changed = r.redactValue(decoded); !changed {
+		return v, false, false
+	}
+	return redacted, drop, true
+}
+
+// redactString replaces the parts matching value patterns, drop is true if RedactDrop is matched.
+func (r *Redactor) redactString(s string) (string, bool) {
+	return r.redactMatches(s, false)
+}
+
+// redactMatches replaces the parts matching value patterns,
+// the parts matched by RedactDrop are masked if dropAsMask, or else drop is returned.
+func (r *Redactor) redactMatches(s string, dropAsMask bool) (string, bool) {
+	for _, rule := range r.rules {
+		strategy := rule.strategy
+		for _, re := range rule.patterns {
+			if !re.MatchString(s) {
+				continue
+			}
+			if strategy == RedactDrop {
+				if !dropAsMask {
+					return "", true
+				}
+				strategy = RedactMask
+			}
+			s = re.ReplaceAllStringFunc(s, func(matched string) string {
+				return r.replace(strategy, matched)
+			})
+		}
+	}
+	return s, false
+}
+
+func (r *Redactor) keyRule(key string) *redactRule {
+	key = strings.ToLower(key)
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.keys[key] {
+			return rule
+		}
+		for _, glob := range rule.globs {
+			if ok, _ := path.Match(glob, key); ok {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) replace(strategy RedactStrategy, s string) string {
+	if strategy == RedactHash {
+		return "hmac:" + hex.EncodeToString(crypto.HMACSHA256(r.hashKey, []byte(s))[:8])
+	}
+	return r.mask
+}
+
+// -------------------------------
+
+type redactedOutput struct {
+	outputWrapper
+	redactor *Redactor
+}
+
+// NewRedactedOutput wraps output to redact pairs by redactor before written.
+func NewRedactedOutput(output Output, redactor *Redactor) Output {
+	return redactedOutput{outputWrapper: outputWrapper{output}, redactor: redactor}
+}
+
+func (o redactedOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o redactedOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	logToOutput(o.Output, pc, l, o.redactor.RedactSubject(subject), o.redactor.Redact(pairs))
+}
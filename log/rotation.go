@@ -0,0 +1,174 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateEvent is passed to the hooks after a time based rotation.
+type RotateEvent struct {
+	// Filename is the file which is being written.
+	Filename string
+	// Backup is the file rotated out, it is empty if not found.
+	Backup string
+	Time   time.Time
+	Err    error
+}
+
+type RotateHook func(event RotateEvent)
+
+type clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
+
+// rotationScheduler calls rotate at every boundary of the period in location,
+// e.g. at midnight for a daily rotation, instead of every period since started.
+type rotationScheduler struct {
+	clock    clock
+	unit     time.Duration
+	n        int
+	location *time.Location
+
+	filename string
+	rotate   func() error
+	hooks    []RotateHook
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newRotationScheduler(c clock, filename string, rotation FileRotation, rotate func() error) *rotationScheduler {
+	unit, ok := rotationPeriodUnit(rotation.RotatePeriod)
+	if !ok {
+		unit = time.Hour * 24
+	}
+	n := rotation.RotateAfter
+	if n <= 0 {
+		n = 1
+	}
+	return &rotationScheduler{
+		clock:    c,
+		unit:     unit,
+		n:        n,
+		location: rotation.location(),
+		filename: filename,
+		rotate:   rotate,
+		hooks:    rotation.AfterRotate,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *rotationScheduler) start() {
+	go s.run()
+}
+
+func (s *rotationScheduler) run() {
+	defer close(s.done)
+	for {
+		now := s.clock.Now()
+		fired, stopTimer := s.clock.NewTimer(s.next(now).Sub(now))
+		select {
+		case <-s.stop:
+			stopTimer()
+			return
+		case t := <-fired:
+			err := s.rotate()
+			event := RotateEvent{Filename: s.filename, Time: t, Err: err}
+			if err == nil {
+				event.Backup = latestBackup(s.filename)
+			}
+			for _, hook := range s.hooks {
+				hook(event)
+			}
+		}
+	}
+}
+
+// Stop ends the scheduling goroutine and waits for it, it can be called more than once.
+func (s *rotationScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// next returns the first boundary after now.
+// Days are counted from the Unix epoch in location, smaller periods from the beginning of the day,
+// and the rotation always happens at midnight.
+func (s *rotationScheduler) next(now time.Time) time.Time {
+	now = now.In(s.location)
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, s.location)
+	if s.unit >= time.Hour*24 {
+		epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, s.location)
+		days := int(midnight.Sub(epoch).Hours()+12) / 24
+		return midnight.AddDate(0, 0, s.n-days%s.n)
+	}
+	step := s.unit * time.Duration(s.n)
+	next := midnight.Add((now.Sub(midnight)/step + 1) * step)
+	if tomorrow := midnight.AddDate(0, 0, 1); next.After(tomorrow) {
+		return tomorrow
+	}
+	return next
+}
+
+func (r FileRotation) location() *time.Location {
+	if r.Location != nil {
+		return r.Location
+	}
+	if r.LocalTime {
+		return time.Local
+	}
+	return time.UTC
+}
+
+// backupTimeFormat is the timestamp in the backup names of lumberjack.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// latestBackup finds the newest backup file named by lumberjack as name-timestamp.ext, or compressed with .gz.
+// The files of other outputs in the same directory are not matched, e.g. app-error.log next to app.log.
+func latestBackup(filename string) string {
+	dir := filepath.Dir(filename)
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filepath.Base(filename), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var latest string
+	var latestTime time.Time
+	for _, it := range entries {
+		name := it.Name()
+		if !strings.HasPrefix(name, prefix) || len(name) < len(prefix)+len(backupTimeFormat) {
+			continue
+		}
+		stamp := name[len(prefix) : len(prefix)+len(backupTimeFormat)]
+		if suffix := name[len(prefix)+len(stamp):]; suffix != ext && suffix != ext+".gz" {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		if latest == "" || t.After(latestTime) {
+			latest, latestTime = name, t
+		}
+	}
+	if latest == "" {
+		return ""
+	}
+	return filepath.Join(dir, latest)
+}
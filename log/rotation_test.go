@@ -0,0 +1,131 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTimer struct {
+	d time.Duration
+	c chan time.Time
+}
+
+type fakeClock struct {
+	now    time.Time
+	timers chan fakeTimer
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, timers: make(chan fakeTimer, 16)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := fakeTimer{d: d, c: make(chan time.Time, 1)}
+	c.timers <- t
+	return t.c, func() bool { return true }
+}
+
+func TestRotationSchedulerNext(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	cases := []struct {
+		rotation FileRotation
+		now      time.Time
+		next     time.Time
+	}{
+		{
+			FileRotation{RotatePeriod: "d", RotateAfter: 1, Location: shanghai},
+			time.Date(2024, 5, 1, 13, 20, 0, 0, shanghai),
+			time.Date(2024, 5, 2, 0, 0, 0, 0, shanghai),
+		},
+		{
+			FileRotation{RotatePeriod: "d", RotateAfter: 1},
+			time.Date(2024, 5, 1, 23, 0, 0, 0, shanghai),
+			time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			FileRotation{RotatePeriod: "h", RotateAfter: 6, Location: time.UTC},
+			time.Date(2024, 5, 1, 13, 20, 0, 0, time.UTC),
+			time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			FileRotation{RotatePeriod: "h", RotateAfter: 7, Location: time.UTC},
+			time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC),
+			time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			FileRotation{RotatePeriod: "m", RotateAfter: 15, Location: time.UTC},
+			time.Date(2024, 5, 1, 13, 15, 0, 0, time.UTC),
+			time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC),
+		},
+	}
+	for i, it := range cases {
+		s := newRotationScheduler(systemClock{}, "", it.rotation, nil)
+		assert.True(t, it.next.Equal(s.next(it.now)), "case %d: %v", i, s.next(it.now))
+	}
+}
+
+func TestRotationSchedulerRun(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "app.log")
+	clock := newFakeClock(time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC))
+	events := make(chan RotateEvent, 1)
+	rotation := FileRotation{
+		RotateOnTime: true,
+		RotatePeriod: "daily",
+		RotateAfter:  1,
+		AfterRotate:  []RotateHook{func(e RotateEvent) { events <- e }},
+	}
+	w := newZapFileWriterWithClock(location, rotation, clock)
+	_, err := w.Write([]byte("first\n"))
+	assert.Nil(t, err)
+
+	timer := <-clock.timers
+	assert.Equal(t, time.Minute, timer.d)
+	clock.now = clock.now.Add(time.Minute)
+	timer.c <- clock.now
+
+	event := <-events
+	assert.Nil(t, event.Err)
+	assert.Equal(t, location, event.Filename)
+	assert.NotEmpty(t, event.Backup)
+	backup, err := os.ReadFile(event.Backup)
+	assert.Nil(t, err)
+	assert.Equal(t, "first\n", string(backup))
+
+	timer = <-clock.timers
+	assert.Equal(t, 24*time.Hour, timer.d)
+
+	closed := make(chan struct{})
+	go func() {
+		assert.Nil(t, w.Close())
+		assert.Nil(t, w.Close())
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler is not stopped")
+	}
+}
+
+func TestLatestBackup(t *testing.T) {
+	dir := t.TempDir()
+	for _, it := range []string{
+		"app.log",
+		"app-2024-05-01T00-00-00.000.log",
+		"app-2024-05-02T00-00-00.000.log.gz",
+		"app-error.log",
+		"app-error-2024-05-03T00-00-00.000.log",
+		"app-2024-05-04T00-00-00.000.txt",
+	} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, it), nil, 0o644))
+	}
+	assert.Equal(t, filepath.Join(dir, "app-2024-05-02T00-00-00.000.log.gz"), latestBackup(filepath.Join(dir, "app.log")))
+	assert.Equal(t, filepath.Join(dir, "app-error-2024-05-03T00-00-00.000.log"), latestBackup(filepath.Join(dir, "app-error.log")))
+	assert.Equal(t, "", latestBackup(filepath.Join(dir, "other.log")))
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Route decides whether a record is written into an Output.
+type Route func(l Level, subject string, pairs []LogPair) bool
+
+// RouteLevels matches the records of levels exactly, unlike the level of Output matching this level and above.
+func RouteLevels(levels ...Level) Route {
+	var set Level
+	for _, it := range levels {
+		set |= it
+	}
+	return func(l Level, _ string, _ []LogPair) bool {
+		return l&set != 0
+	}
+}
+
+// RouteLevelRange matches the records whose level is in [min, max].
+func RouteLevelRange(min, max Level) Route {
+	return func(l Level, _ string, _ []LogPair) bool {
+		return l >= min && l <= max
+	}
+}
+
+// RouteSubjectPrefix matches the records whose subject has one of prefixes.
+func RouteSubjectPrefix(prefixes ...string) Route {
+	return func(_ Level, subject string, _ []LogPair) bool {
+		for _, it := range prefixes {
+			if strings.HasPrefix(subject, it) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RouteHasKey matches the records having a pair of one of keys, trace pairs included.
+func RouteHasKey(keys ...string) Route {
+	return func(_ Level, _ string, pairs []LogPair) bool {
+		for _, pair := range pairs {
+			for _, key := range keys {
+				if pair.key == key {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// RouteAll matches the records matched by all of routes.
+func RouteAll(routes ...Route) Route {
+	return func(l Level, subject string, pairs []LogPair) bool {
+		for _, it := range routes {
+			if !it(l, subject, pairs) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RouteAny matches the records matched by any of routes.
+func RouteAny(routes ...Route) Route {
+	return func(l Level, subject string, pairs []LogPair) bool {
+		for _, it := range routes {
+			if it(l, subject, pairs) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RouteNot matches the records not matched by route.
+func RouteNot(route Route) Route {
+	return func(l Level, subject string, pairs []LogPair) bool {
+		return !route(l, subject, pairs)
+	}
+}
+
+// -------------------------------
+
+type routedOutput struct {
+	outputWrapper
+	route Route
+}
+
+// NewRoutedOutput wraps output to write only the records matched by route,
+// the level of output still applies.
+// e.g. NewRoutedOutput(MakeFileOutput(...), RouteLevels(LevelWarn)) makes a file of warnings only.
+func NewRoutedOutput(output Output, route Route) Output {
+	return routedOutput{outputWrapper: outputWrapper{output}, route: route}
+}
+
+func (o routedOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o routedOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	if o.route(l, subject, pairs) {
+		logToOutput(o.Output, pc, l, subject, pairs)
+	}
+}
+
+// -------------------------------
+
+// RouteConfig describes a Route, the conditions set are all required.
+type RouteConfig struct {
+	// Levels are the level names matched exactly.
+	Levels []string `yaml:"levels" json:"levels"`
+	// MinLevel and MaxLevel are the bounds of level, inclusive.
+	MinLevel        string   `yaml:"min_level" json:"min_level"`
+	MaxLevel        string   `yaml:"max_level" json:"max_level"`
+	SubjectPrefixes []string `yaml:"subject_prefixes" json:"subject_prefixes"`
+	// Keys matches the records having any of the keys.
+	Keys []string `yaml:"keys" json:"keys"`
+
+	// ExcludeSubjectPrefixes and ExcludeKeys drop the records matched by them.
+	ExcludeSubjectPrefixes []string `yaml:"exclude_subject_prefixes" json:"exclude_subject_prefixes"`
+	ExcludeKeys            []string `yaml:"exclude_keys" json:"exclude_keys"`
+}
+
+func (cfg RouteConfig) route() (Route, error) {
+	var routes []Route
+	if len(cfg.Levels) > 0 {
+		levels := make([]Level, 0, len(cfg.Levels))
+		for _, it := range cfg.Levels {
+			level, err := ParseLevel(it)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, level)
+		}
+		routes = append(routes, RouteLevels(levels...))
+	}
+	if cfg.MinLevel != "" || cfg.MaxLevel != "" {
+		min, max := LevelDebug, LevelFatal
+		var err error
+		if cfg.MinLevel != "" {
+			if min, err = ParseLevel(cfg.MinLevel); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.MaxLevel != "" {
+			if max, err = ParseLevel(cfg.MaxLevel); err != nil {
+				return nil, err
+			}
+		}
+		if min > max {
+			return nil, fmt.Errorf("log: route min_level %s is above max_level %s", cfg.MinLevel, cfg.MaxLevel)
+		}
+		routes = append(routes, RouteLevelRange(min, max))
+	}
+	if len(cfg.SubjectPrefixes) > 0 {
+		routes = append(routes, RouteSubjectPrefix(cfg.SubjectPrefixes...))
+	}
+	if len(cfg.Keys) > 0 {
+		routes = append(routes, RouteHasKey(cfg.Keys...))
+	}
+	if len(cfg.ExcludeSubjectPrefixes) > 0 {
+		routes = append(routes, RouteNot(RouteSubjectPrefix(cfg.ExcludeSubjectPrefixes...)))
+	}
+	if len(cfg.ExcludeKeys) > 0 {
+		routes = append(routes, RouteNot(RouteHasKey(cfg.ExcludeKeys...)))
+	}
+	return RouteAll(routes...), nil
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutedOutput(t *testing.T) {
+	warns := NewRecorder("warns", LevelDebug)
+	errs := NewRecorder("errors", LevelDebug)
+	access := NewRecorder("access", LevelInfo)
+	app := NewRecorder("app", LevelInfo)
+	logger := NewLogger(
+		NewRoutedOutput(warns, RouteLevels(LevelWarn)),
+		NewRoutedOutput(errs, RouteLevelRange(LevelError, LevelFatal)),
+		NewRoutedOutput(access, RouteAny(RouteSubjectPrefix("http "), RouteHasKey("status"))),
+		NewRoutedOutput(app, RouteNot(RouteSubjectPrefix("http "))),
+	)
+	logger.Debug("debug")
+	logger.Info("http request", Int("status", 200))
+	logger.Info("served", Int("status", 200))
+	logger.Warn("slow")
+	logger.Error("failed")
+
+	assert.Equal(t, []string{"slow"}, warns.Subjects())
+	assert.Equal(t, []string{"failed"}, errs.Subjects())
+	assert.Equal(t, []string{"http request", "served"}, access.Subjects())
+	assert.Equal(t, []string{"served", "slow", "failed"}, app.Subjects())
+
+	// trace pairs are matched too
+	logger.WithTraceLogs(Int("status", 500)).Info("traced")
+	last, _ := access.Last()
+	assert.Equal(t, "traced", last.Subject)
+
+	level, ok := logger.OutputLevel("access")
+	assert.True(t, ok)
+	assert.Equal(t, LevelInfo, level)
+	assert.Nil(t, logger.SetOutputLevel("access", LevelError))
+	assert.Equal(t, LevelError, access.Level())
+
+	// wrapping an output whose level can not be changed
+	fixed := NewLogger(NewRoutedOutput(fixedOutput{name: "fixed"}, RouteLevels(LevelWarn)))
+	assert.ErrorContains(t, fixed.SetOutputLevel("fixed", LevelError), "can not be changed")
+	assert.ErrorContains(t, NewLogger(NewTeeOutput("tee", NewRoutedOutput(fixedOutput{}, RouteLevels(LevelWarn)))).SetOutputLevel("tee", LevelError), "can not be changed")
+}
+
+// fixedOutput is a named Output not implementing LevelSetter.
+type fixedOutput struct {
+	name string
+}
+
+func (o fixedOutput) Name() string                               { return o.name }
+func (o fixedOutput) Level() Level                               { return LevelInfo }
+func (o fixedOutput) LogModuleAndPairs(Level, string, []LogPair) {}
+
+func TestRouteConfig(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Name: "warn", Type: "file", Location: filepath.Join(dir, "warn.log"), Route: &RouteConfig{Levels: []string{"warn"}}},
+		{Name: "error", Type: "file", Location: filepath.Join(dir, "error.log"), Route: &RouteConfig{MinLevel: "error"}},
+		{Name: "access", Type: "file", Location: filepath.Join(dir, "access.log"), Route: &RouteConfig{SubjectPrefixes: []string{"http "}}},
+		{Name: "app", Type: "file", Location: filepath.Join(dir, "app.log"), Route: &RouteConfig{MaxLevel: "warn", ExcludeSubjectPrefixes: []string{"http "}, ExcludeKeys: []string{"secret"}}},
+	}})
+	assert.Nil(t, err)
+	logger.Info("http request")
+	logger.Info("started")
+	logger.Info("hidden", String("secret", "x"))
+	logger.Warn("slow")
+	logger.Error("failed")
+	assert.Nil(t, logger.Close())
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		return string(data)
+	}
+	assert.Equal(t, 1, strings.Count(read("warn.log"), "\n"))
+	assert.Contains(t, read("warn.log"), "slow")
+	assert.Equal(t, 1, strings.Count(read("error.log"), "\n"))
+	assert.Contains(t, read("error.log"), "failed")
+	assert.Equal(t, 1, strings.Count(read("access.log"), "\n"))
+	assert.Contains(t, read("access.log"), "http request")
+	assert.Equal(t, 2, strings.Count(read("app.log"), "\n"))
+	assert.Contains(t, read("app.log"), "started")
+	assert.NotContains(t, read("app.log"), "hidden")
+
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "console", Route: &RouteConfig{Levels: []string{"verbose"}}},
+	}})
+	assert.NotNil(t, err)
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "console", Route: &RouteConfig{MinLevel: "error", MaxLevel: "info"}},
+	}})
+	assert.ErrorContains(t, err, "min_level error is above max_level info")
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{
+		{Type: "console", Route: &RouteConfig{MaxLevel: "loud"}},
+	}})
+	assert.NotNil(t, err)
+}
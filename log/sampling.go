@@ -0,0 +1,183 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const subjectSamplingDropped = "log records dropped by sampling"
+
+// SamplingConfig limits the records of each (level, subject) in every Tick:
+// the First records are logged, then every Thereafter-th one.
+type SamplingConfig struct {
+	// Tick is the window of counting, default is one second.
+	Tick  time.Duration
+	First int
+	// Thereafter is zero to drop all records after the First ones, which makes a rate limiter.
+	Thereafter int
+	// SummaryInterval is the interval to log how many records were dropped, zero disables the summary.
+	SummaryInterval time.Duration
+}
+
+type samplingKey struct {
+	level   Level
+	subject string
+}
+
+type samplingCounter struct {
+	windowEnd time.Time
+	count     int
+}
+
+// SampledOutput samples the records written into the wrapped Output.
+type SampledOutput struct {
+	outputWrapper
+	cfg   SamplingConfig
+	clock clock
+
+	mu       sync.Mutex
+	counters map[samplingKey]*samplingCounter
+	// the expired counters are removed every Tick
+	nextPrune time.Time
+	// dropped counts every key for the summary, droppedTotal is used without the summary
+	dropped      map[samplingKey]int
+	droppedTotal int
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSampledOutput wraps output to sample records as cfg,
+// close it to stop the summary and close the wrapped output.
+func NewSampledOutput(output Output, cfg SamplingConfig) *SampledOutput {
+	return newSampledOutput(output, cfg, systemClock{})
+}
+
+func newSampledOutput(output Output, cfg SamplingConfig, c clock) *SampledOutput {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	o := &SampledOutput{
+		outputWrapper: outputWrapper{output},
+		cfg:           cfg,
+		clock:         c,
+		counters:      make(map[samplingKey]*samplingCounter),
+		dropped:       make(map[samplingKey]int),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if cfg.SummaryInterval > 0 {
+		go o.runSummary()
+	} else {
+		close(o.done)
+	}
+	return o
+}
+
+func (o *SampledOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o *SampledOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	if o.sample(samplingKey{level: l, subject: subject}) {
+		logToOutput(o.Output, pc, l, subject, pairs)
+	}
+}
+
+func (o *SampledOutput) sample(key samplingKey) bool {
+	now := o.clock.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !now.Before(o.nextPrune) {
+		o.pruneCounters(now)
+		o.nextPrune = now.Add(o.cfg.Tick)
+	}
+	counter := o.counters[key]
+	if counter == nil || !now.Before(counter.windowEnd) {
+		counter = &samplingCounter{windowEnd: now.Add(o.cfg.Tick)}
+		o.counters[key] = counter
+	}
+	counter.count++
+	n := counter.count - o.cfg.First
+	if n <= 0 || (o.cfg.Thereafter > 0 && n%o.cfg.Thereafter == 0) {
+		return true
+	}
+	o.droppedTotal++
+	if o.cfg.SummaryInterval > 0 {
+		o.dropped[key]++
+	}
+	return false
+}
+
+// pruneCounters removes the expired counters, e.g. of the subjects formatted with ids, should be called with mu locked.
+func (o *SampledOutput) pruneCounters(now time.Time) {
+	for key, counter := range o.counters {
+		if !now.Before(counter.windowEnd) {
+			delete(o.counters, key)
+		}
+	}
+}
+
+// Dropped returns how many records were dropped since the last summary.
+func (o *SampledOutput) Dropped() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.droppedTotal
+}
+
+func (o *SampledOutput) runSummary() {
+	defer close(o.done)
+	for {
+		fired, stopTimer := o.clock.NewTimer(o.cfg.SummaryInterval)
+		select {
+		case <-o.stop:
+			stopTimer()
+			return
+		case <-fired:
+			o.logSummary()
+		}
+	}
+}
+
+// logSummary logs the dropped count of every key with its level.
+func (o *SampledOutput) logSummary() {
+	o.mu.Lock()
+	dropped := o.dropped
+	o.dropped = make(map[samplingKey]int)
+	o.droppedTotal = 0
+	o.mu.Unlock()
+
+	keys := make([]samplingKey, 0, len(dropped))
+	for key := range dropped {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].subject < keys[j].subject
+	})
+	for _, key := range keys {
+		if key.level >= o.Level() {
+			// the summary has no caller
+			logToOutput(o.Output, 0, key.level, subjectSamplingDropped, []LogPair{
+				String("subject", key.subject),
+				Any("dropped", dropped[key]),
+			})
+		}
+	}
+}
+
+// Close logs the last summary, then closes the wrapped output.
+func (o *SampledOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+		<-o.done
+		if o.cfg.SummaryInterval > 0 {
+			o.logSummary()
+		}
+	})
+	return o.outputWrapper.Close()
+}
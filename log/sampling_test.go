@@ -0,0 +1,75 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledOutput(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	collected := NewRecorder("", LevelDebug)
+	o := newSampledOutput(collected, SamplingConfig{First: 2, Thereafter: 3, SummaryInterval: time.Minute}, clock)
+	l := NewLogger(o)
+
+	for i := 0; i < 8; i++ {
+		l.Warn("a")
+	}
+	l.Info("b")
+	// 1, 2, 5 and 8 of "a" are logged
+	assert.Equal(t, []string{"a", "a", "a", "a", "b"}, collected.Subjects())
+	assert.Equal(t, 4, o.Dropped())
+
+	clock.now = clock.now.Add(time.Second)
+	l.Warn("a")
+	assert.Equal(t, 6, len(collected.Subjects()))
+
+	timer := <-clock.timers
+	assert.Equal(t, time.Minute, timer.d)
+	timer.c <- clock.now
+	<-clock.timers
+	assert.Equal(t, 0, o.Dropped())
+	summary, _ := collected.Last()
+	assert.Equal(t, subjectSamplingDropped, summary.Subject)
+	assert.Equal(t, LevelWarn, summary.Level)
+	assert.Equal(t, []LogPair{String("subject", "a"), Any("dropped", 4)}, summary.Pairs)
+
+	assert.Nil(t, o.Close())
+	assert.Nil(t, o.Close())
+}
+
+func TestSampledOutputRateLimit(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	collected := NewRecorder("", LevelDebug)
+	l := NewLogger(newSampledOutput(collected, SamplingConfig{First: 1}, clock))
+	l.Error("a")
+	l.Error("a")
+	l.Error("b")
+	assert.Equal(t, []string{"a", "b"}, collected.Subjects())
+
+	sampled := NewSampledOutput(NewRecorder("", LevelDebug), SamplingConfig{First: 1})
+	l = NewLogger(sampled)
+	l.Error("a")
+	l.Error("a")
+	assert.Equal(t, 1, sampled.Dropped())
+	assert.Nil(t, l.Close())
+}
+
+func TestSampledOutputPrune(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	o := newSampledOutput(NewRecorder("", LevelDebug), SamplingConfig{First: 1}, clock)
+	l := NewLogger(o)
+	for i := 0; i < 100; i++ {
+		l.Warnf("user %d not found", i)
+		l.Warnf("user %d not found", i)
+	}
+	assert.Equal(t, 100, len(o.counters))
+	assert.Equal(t, 100, o.Dropped())
+	assert.Equal(t, 0, len(o.dropped))
+
+	// the expired counters are removed without the summary
+	clock.now = clock.now.Add(time.Second)
+	l.Warn("next")
+	assert.Equal(t, 1, len(o.counters))
+}
@@ -0,0 +1,499 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type ShipFormat string
+
+const (
+	// ShipFormatJSONLines posts records as newline delimited JSON.
+	ShipFormatJSONLines ShipFormat = "jsonl"
+	// ShipFormatLoki posts records to the push API of Loki.
+	ShipFormatLoki ShipFormat = "loki"
+	// ShipFormatElasticsearch posts records to the bulk API of Elasticsearch.
+	ShipFormatElasticsearch ShipFormat = "elasticsearch"
+)
+
+const (
+	defaultShipBatchSize     = 500
+	defaultShipBatchBytes    = 1 << 20
+	defaultShipFlushInterval = time.Second
+	defaultShipMaxRetries    = 3
+	defaultShipRetryBackoff  = 500 * time.Millisecond
+	defaultShipMaxBackoff    = 30 * time.Second
+	defaultShipTimeout       = 10 * time.Second
+
+	shipSpoolExt = ".batch"
+)
+
+type ShipConfig struct {
+	URL     string            `yaml:"url" json:"url"`
+	Format  ShipFormat        `yaml:"format" json:"format"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Labels are the stream labels of Loki.
+	Labels map[string]string `yaml:"labels" json:"labels"`
+	// Index is the index of Elasticsearch.
+	Index string `yaml:"index" json:"index"`
+
+	// A batch is posted when it has BatchSize records or BatchBytes bytes, or every FlushInterval.
+	BatchSize     int           `yaml:"batch_size" json:"batch_size"`
+	BatchBytes    int           `yaml:"batch_bytes" json:"batch_bytes"`
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+	Gzip          bool          `yaml:"gzip" json:"gzip"`
+	// Timeout bounds every post, and the waiting of Sync for the records to be posted or spooled.
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// A failed batch is retried MaxRetries times, waiting RetryBackoff doubled every time up to 30s.
+	MaxRetries   int           `yaml:"max_retries" json:"max_retries"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" json:"retry_backoff"`
+
+	// SpoolDir keeps the batches failed after retries, they are posted again once the collector is back.
+	// The batches are spooled too instead of blocking the logging when the queue of batches is full.
+	// Without it, those batches are dropped and the count is reported by OnError.
+	SpoolDir string `yaml:"spool_dir" json:"spool_dir"`
+	// SpoolMaxBytes limits the size of SpoolDir, the oldest batches are removed first, zero means no limit.
+	SpoolMaxBytes int64 `yaml:"spool_max_bytes" json:"spool_max_bytes"`
+
+	Client *http.Client `yaml:"-" json:"-"`
+	// OnError receives the errors of shipping, default writes them to stderr.
+	OnError func(error) `yaml:"-" json:"-"`
+}
+
+// UnmarshalJSON accepts the durations as strings like "1s", or integer nanoseconds.
+func (cfg *ShipConfig) UnmarshalJSON(data []byte) error {
+	type plain ShipConfig
+	return json.Unmarshal(data, &struct {
+		*plain
+		FlushInterval *jsonDuration `json:"flush_interval"`
+		Timeout       *jsonDuration `json:"timeout"`
+		RetryBackoff  *jsonDuration `json:"retry_backoff"`
+	}{
+		plain:         (*plain)(cfg),
+		FlushInterval: (*jsonDuration)(&cfg.FlushInterval),
+		Timeout:       (*jsonDuration)(&cfg.Timeout),
+		RetryBackoff:  (*jsonDuration)(&cfg.RetryBackoff),
+	})
+}
+
+func (cfg *ShipConfig) setDefaults() error {
+	if cfg.URL == "" {
+		return errors.New("log: url is required by ship output")
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = ShipFormatJSONLines
+	case ShipFormatJSONLines, ShipFormatLoki:
+	case ShipFormatElasticsearch:
+		if cfg.Index == "" {
+			return errors.New("log: index is required by elasticsearch format")
+		}
+	default:
+		return fmt.Errorf("log: unknown ship format %q", cfg.Format)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultShipBatchSize
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = defaultShipBatchBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultShipFlushInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultShipTimeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultShipMaxRetries
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultShipRetryBackoff
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) { fmt.Fprintln(os.Stderr, err) }
+	}
+	if cfg.SpoolDir != "" {
+		if err := os.MkdirAll(cfg.SpoolDir, 0o700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MakeShipOutput makes an Output posting batches of JSON records to an HTTP collector,
+// the records are encoded by the keys of fmt in JSON whatever fmt.Format is.
+// Close it to post the last batch.
+func MakeShipOutput(name string, fmt LocalFormat, level Level, cfg ShipConfig) (Output, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return nil, err
+	}
+	s := newShipper(cfg)
+	fmt.Format = MessageFormatJSON
+	output := newZapLogger(name, fmt, level, s)
+	output.closer = s
+	return output, nil
+}
+
+type shipLine struct {
+	at   time.Time
+	data []byte
+}
+
+type shipBatch struct {
+	lines []shipLine
+	// flushed is set for the batch of Sync, it receives the error of shipping the batch
+	flushed chan error
+}
+
+// shipper is the zapcore.WriteSyncer receiving one encoded record by every Write.
+type shipper struct {
+	cfg ShipConfig
+
+	// sendMu guards closed and the sending to queue, it's locked by Close to close queue.
+	sendMu sync.RWMutex
+	closed bool
+
+	mu         sync.Mutex
+	lines      []shipLine
+	batchBytes int
+
+	queue   chan shipBatch
+	done    chan struct{}
+	down    atomic.Bool
+	dropped atomic.Uint64
+	spoolID atomic.Uint64
+}
+
+func newShipper(cfg ShipConfig) *shipper {
+	s := &shipper{cfg: cfg, queue: make(chan shipBatch, 16), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *shipper) Write(p []byte) (int, error) {
+	line := shipLine{at: time.Now(), data: bytes.TrimRight(append([]byte(nil), p...), "\n")}
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	s.mu.Lock()
+	s.lines = append(s.lines, line)
+	s.batchBytes += len(line.data)
+	var full []shipLine
+	if len(s.lines) >= s.cfg.BatchSize || s.batchBytes >= s.cfg.BatchBytes {
+		full = s.takeLinesLocked()
+	}
+	s.mu.Unlock()
+	if full != nil {
+		select {
+		case s.queue <- shipBatch{lines: full}:
+		default:
+			// never block the logging on a slow collector
+			s.overflow(full)
+		}
+	}
+	return len(p), nil
+}
+
+// overflow spools or drops lines which can't be queued.
+func (s *shipper) overflow(lines []shipLine) {
+	if s.cfg.SpoolDir != "" {
+		s.spool(s.encode(lines))
+		return
+	}
+	s.dropped.Add(uint64(len(lines)))
+}
+
+func (s *shipper) takeLines() []shipLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.takeLinesLocked()
+}
+
+func (s *shipper) takeLinesLocked() []shipLine {
+	lines := s.lines
+	s.lines = nil
+	s.batchBytes = 0
+	return lines
+}
+
+// Sync waits for the records written before are posted or spooled at most Timeout,
+// the error of posting or spooling them is returned.
+func (s *shipper) Sync() error {
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+	s.sendMu.RLock()
+	if s.closed {
+		s.sendMu.RUnlock()
+		return nil
+	}
+	flushed := make(chan error, 1)
+	select {
+	case s.queue <- shipBatch{lines: s.takeLines(), flushed: flushed}:
+	case <-timer.C:
+		s.sendMu.RUnlock()
+		return errShipSyncTimeout
+	}
+	s.sendMu.RUnlock()
+	select {
+	case err := <-flushed:
+		return err
+	case <-timer.C:
+		return errShipSyncTimeout
+	}
+}
+
+var errShipSyncTimeout = errors.New("log: ship sync timed out, the records are still being shipped")
+
+func (s *shipper) Close() error {
+	s.sendMu.Lock()
+	if s.closed {
+		s.sendMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.queue <- shipBatch{lines: s.takeLines()}
+	close(s.queue)
+	s.sendMu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *shipper) run() {
+	defer close(s.done)
+	defer s.reportDropped()
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			err := s.ship(batch.lines)
+			if batch.flushed != nil {
+				batch.flushed <- err
+			}
+		case <-ticker.C:
+			s.ship(s.takeLines())
+			s.drainSpool()
+		}
+	}
+}
+
+// ship posts lines, or spools or drops them if the collector is down.
+// The error is returned if lines are not posted, even if they are spooled.
+func (s *shipper) ship(lines []shipLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	payload := s.encode(lines)
+	if s.down.Load() {
+		if s.cfg.SpoolDir != "" {
+			// drainSpool finds out when the collector is back
+			return errors.Join(fmt.Errorf("log: ship %d records spooled, the collector is down", len(lines)), s.spool(payload))
+		}
+		// the collector is known to be down, try it once without retries
+		if _, err := s.post(payload); err != nil {
+			s.dropped.Add(uint64(len(lines)))
+			return fmt.Errorf("log: ship %d records dropped, the collector is down: %w", len(lines), err)
+		}
+		s.down.Store(false)
+		s.reportDropped()
+		return nil
+	}
+	if retryable, err := s.postWithRetry(payload); err != nil {
+		err = fmt.Errorf("log: ship %d records: %w", len(lines), err)
+		s.cfg.OnError(err)
+		if !retryable {
+			return err
+		}
+		s.down.Store(true)
+		if s.cfg.SpoolDir != "" {
+			return errors.Join(err, s.spool(payload))
+		}
+		s.dropped.Add(uint64(len(lines)))
+		return err
+	}
+	s.reportDropped()
+	return nil
+}
+
+func (s *shipper) reportDropped() {
+	if n := s.dropped.Swap(0); n > 0 {
+		s.cfg.OnError(fmt.Errorf("log: ship dropped %d records", n))
+	}
+}
+
+func (s *shipper) encode(lines []shipLine) []byte {
+	buf := &bytes.Buffer{}
+	switch s.cfg.Format {
+	case ShipFormatLoki:
+		values := make([][2]string, 0, len(lines))
+		for _, it := range lines {
+			values = append(values, [2]string{strconv.FormatInt(it.at.UnixNano(), 10), string(it.data)})
+		}
+		labels := s.cfg.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		json.NewEncoder(buf).Encode(map[string]interface{}{
+			"streams": []interface{}{map[string]interface{}{"stream": labels, "values": values}},
+		})
+	case ShipFormatElasticsearch:
+		action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": s.cfg.Index}})
+		for _, it := range lines {
+			buf.Write(action)
+			buf.WriteByte('\n')
+			buf.Write(it.data)
+			buf.WriteByte('\n')
+		}
+	default:
+		for _, it := range lines {
+			buf.Write(it.data)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// postWithRetry returns whether the last error is retryable.
+func (s *shipper) postWithRetry(payload []byte) (retryable bool, err error) {
+	backoff := s.cfg.RetryBackoff
+	for i := 0; i <= s.cfg.MaxRetries; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, defaultShipMaxBackoff)
+		}
+		if retryable, err = s.post(payload); err == nil || !retryable {
+			return retryable, err
+		}
+	}
+	return retryable, err
+}
+
+// post returns whether the error is retryable.
+func (s *shipper) post(payload []byte) (bool, error) {
+	body := payload
+	if s.cfg.Gzip {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		zw.Write(payload)
+		zw.Close()
+		body = buf.Bytes()
+	}
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	if s.cfg.Format == ShipFormatLoki {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// -------------------------------
+
+// spool writes payload into SpoolDir readable only by the owner, the error is reported by OnError too.
+func (s *shipper) spool(payload []byte) error {
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.spoolID.Add(1)%1000000, shipSpoolExt)
+	if err := os.WriteFile(filepath.Join(s.cfg.SpoolDir, name), payload, 0o600); err != nil {
+		err = fmt.Errorf("log: spool: %w", err)
+		s.cfg.OnError(err)
+		return err
+	}
+	if s.cfg.SpoolMaxBytes > 0 {
+		s.trimSpool()
+	}
+	return nil
+}
+
+func (s *shipper) spooledFiles() []string {
+	files, _ := filepath.Glob(filepath.Join(s.cfg.SpoolDir, "*"+shipSpoolExt))
+	sort.Strings(files)
+	return files
+}
+
+// trimSpool removes the oldest batches until the spool is not larger than SpoolMaxBytes.
+func (s *shipper) trimSpool() {
+	files := s.spooledFiles()
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, it := range files {
+		if info, err := os.Stat(it); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; total > s.cfg.SpoolMaxBytes && i < len(files); i++ {
+		os.Remove(files[i])
+		total -= sizes[i]
+	}
+}
+
+// drainSpool posts the spooled batches from the oldest, and stops at the first failure.
+func (s *shipper) drainSpool() {
+	if s.cfg.SpoolDir == "" {
+		return
+	}
+	for _, it := range s.spooledFiles() {
+		payload, err := os.ReadFile(it)
+		if err != nil {
+			continue
+		}
+		retryable, err := s.post(payload)
+		if err != nil && retryable {
+			s.down.Store(true)
+			return
+		}
+		if err != nil {
+			s.cfg.OnError(fmt.Errorf("log: ship spooled %s: %w", filepath.Base(it), err))
+		}
+		os.Remove(it)
+	}
+	s.down.Store(false)
+}
+
+var _ zapcore.WriteSyncer = (*shipper)(nil)
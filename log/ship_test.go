@@ -0,0 +1,279 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shipCollector struct {
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	failures atomic.Int32
+}
+
+func (c *shipCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.failures.Load() > 0 {
+		c.failures.Add(-1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := io.ReadAll(body)
+	c.mu.Lock()
+	c.bodies = append(c.bodies, string(data))
+	c.headers = append(c.headers, r.Header.Clone())
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *shipCollector) lines() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []map[string]interface{}
+	for _, body := range c.bodies {
+		scanner := bufio.NewScanner(strings.NewReader(body))
+		for scanner.Scan() {
+			record := map[string]interface{}{}
+			json.Unmarshal(scanner.Bytes(), &record)
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestShipOutputJSONLines(t *testing.T) {
+	c := &shipCollector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	output, err := MakeShipOutput("ship", MakeLocalFormat(MessageFormatText), LevelDebug, ShipConfig{
+		URL: server.URL, BatchSize: 2, FlushInterval: time.Hour, Gzip: true,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	assert.Nil(t, err)
+	logger := NewLogger(output)
+	logger.Info("first", String("k", "v1"))
+	logger.Info("second", Int("n", 2))
+	logger.Warn("third")
+	assert.Nil(t, logger.Sync())
+
+	records := c.lines()
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, "first", records[0]["msg"])
+	assert.Equal(t, "v1", records[0]["k"])
+	assert.Equal(t, float64(2), records[1]["n"])
+	assert.Equal(t, "third", records[2]["msg"])
+	assert.Equal(t, 2, len(c.bodies))
+	assert.Equal(t, "Bearer token", c.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/x-ndjson", c.headers[0].Get("Content-Type"))
+	assert.Nil(t, logger.Close())
+}
+
+func TestShipOutputLokiAndElasticsearch(t *testing.T) {
+	c := &shipCollector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	loki, err := MakeShipOutput("loki", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{
+		URL: server.URL, Format: ShipFormatLoki, Labels: map[string]string{"app": "test"},
+	})
+	assert.Nil(t, err)
+	NewLogger(loki).Info("hello")
+	assert.Nil(t, NewLogger(loki).Close())
+
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(c.bodies[0]), &push))
+	assert.Equal(t, "test", push.Streams[0].Stream["app"])
+	assert.Equal(t, 1, len(push.Streams[0].Values))
+	assert.Contains(t, push.Streams[0].Values[0][1], `"msg":"hello"`)
+
+	_, err = MakeShipOutput("es", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{URL: server.URL, Format: ShipFormatElasticsearch})
+	assert.NotNil(t, err)
+	es, err := MakeShipOutput("es", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{
+		URL: server.URL, Format: ShipFormatElasticsearch, Index: "logs",
+	})
+	assert.Nil(t, err)
+	NewLogger(es).Info("hello")
+	assert.Nil(t, NewLogger(es).Close())
+	lines := strings.Split(strings.TrimSpace(c.bodies[1]), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, `{"index":{"_index":"logs"}}`, lines[0])
+	assert.Contains(t, lines[1], `"msg":"hello"`)
+}
+
+func TestShipOutputRetry(t *testing.T) {
+	c := &shipCollector{}
+	c.failures.Store(2)
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	var errs atomic.Int32
+	output, err := MakeShipOutput("ship", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{
+		URL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond,
+		OnError: func(error) { errs.Add(1) },
+	})
+	assert.Nil(t, err)
+	logger := NewLogger(output)
+	logger.Info("retried")
+	assert.Nil(t, logger.Close())
+	assert.Equal(t, 1, len(c.lines()))
+	assert.Equal(t, int32(0), errs.Load())
+}
+
+func TestShipOutputSpool(t *testing.T) {
+	c := &shipCollector{}
+	c.failures.Store(1 << 20)
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "spool")
+	var errs atomic.Int32
+	output, err := MakeShipOutput("ship", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{
+		URL: server.URL, MaxRetries: -1, FlushInterval: 20 * time.Millisecond, SpoolDir: dir,
+		OnError: func(error) { errs.Add(1) },
+	})
+	assert.Nil(t, err)
+	logger := NewLogger(output)
+	logger.Info("first")
+	assert.ErrorContains(t, logger.Sync(), "log: ship 1 records: status 503")
+	logger.Info("second")
+	assert.ErrorContains(t, logger.Sync(), "log: ship 1 records spooled, the collector is down")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.batch"))
+	assert.Equal(t, 2, len(files))
+	info, _ := os.Stat(dir)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	info, _ = os.Stat(files[0])
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	// only the first failure is reported, the next batch is spooled directly
+	assert.Equal(t, int32(1), errs.Load())
+
+	// the collector is back
+	c.failures.Store(0)
+	assert.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.batch"))
+		return len(files) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	records := c.lines()
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "first", records[0]["msg"])
+	assert.Equal(t, "second", records[1]["msg"])
+
+	logger.Info("third")
+	assert.Nil(t, logger.Close())
+	assert.Equal(t, 3, len(c.lines()))
+	logger.Info("closed")
+	assert.Equal(t, 3, len(c.lines()))
+}
+
+func TestShipOutputCollectorDown(t *testing.T) {
+	release := make(chan struct{})
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var mu sync.Mutex
+	var errs []string
+	output, err := MakeShipOutput("ship", MakeLocalFormat(MessageFormatJSON), LevelDebug, ShipConfig{
+		URL: server.URL, BatchSize: 1, Timeout: 50 * time.Millisecond, MaxRetries: -1,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err.Error())
+			mu.Unlock()
+		},
+	})
+	assert.Nil(t, err)
+	logger := NewLogger(output)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		logger.Info("blocked")
+	}
+	// the collector hangs, but the logging doesn't wait for it
+	assert.Less(t, time.Since(start), time.Second)
+	// and Sync waits at most Timeout
+	start = time.Now()
+	assert.NotNil(t, logger.Sync())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorContains(t, logger.Close(), "log: ship")
+	// the collector is down after the first batch, the queued batches are tried once and the others are dropped
+	assert.Less(t, int(posts.Load()), 20)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, errs[0], "log: ship 1 records")
+	assert.Equal(t, "log: ship dropped 100 records", errs[len(errs)-1])
+}
+
+func TestShipOutputSpoolMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	s := &shipper{cfg: ShipConfig{SpoolDir: dir, SpoolMaxBytes: 10}}
+	s.spool([]byte("123456"))
+	s.spool([]byte("abcdef"))
+	files := s.spooledFiles()
+	assert.Equal(t, 1, len(files))
+	data, _ := os.ReadFile(files[0])
+	assert.True(t, bytes.Equal([]byte("abcdef"), data))
+}
+
+func TestShipOutputConfig(t *testing.T) {
+	_, err := NewLoggerFromConfig(Config{Outputs: []OutputConfig{{Type: OutputTypeShip}}})
+	assert.NotNil(t, err)
+	_, err = NewLoggerFromConfig(Config{Outputs: []OutputConfig{{Type: OutputTypeShip, Ship: ShipConfig{URL: "http://127.0.0.1", Format: "xml"}}}})
+	assert.NotNil(t, err)
+	logger, err := NewLoggerFromConfig(Config{Outputs: []OutputConfig{{Type: OutputTypeShip, Ship: ShipConfig{URL: "http://127.0.0.1"}}}})
+	assert.Nil(t, err)
+	assert.Nil(t, logger.Close())
+}
+
+func TestShipConfigJSON(t *testing.T) {
+	var cfg Config
+	raw := `{"outputs": [{"type": "ship", "ship": {
+		"url": "http://127.0.0.1", "batch_size": 10, "flush_interval": "1.5s", "timeout": 2000000000, "retry_backoff": "100ms"
+	}}]}`
+	assert.Nil(t, json.Unmarshal([]byte(raw), &cfg))
+	ship := cfg.Outputs[0].Ship
+	assert.Equal(t, "http://127.0.0.1", ship.URL)
+	assert.Equal(t, 10, ship.BatchSize)
+	assert.Equal(t, 1500*time.Millisecond, ship.FlushInterval)
+	assert.Equal(t, 2*time.Second, ship.Timeout)
+	assert.Equal(t, 100*time.Millisecond, ship.RetryBackoff)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"flush_interval": "soon"}`), &ShipConfig{}))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"timeout": true}`), &ShipConfig{}))
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// -------------------------------
+// slog.Handler backed by Logger
+
+type slogHandler struct {
+	logger Logger
+	// prefix of keys in the current group, e.g. "a.b."
+	prefix string
+}
+
+// NewSlogHandler returns a slog.Handler writing into l,
+// attrs are logged as pairs, groups are flattened into dotted keys,
+// and the trace of the Logger in the context passed to slog is logged too.
+func NewSlogHandler(l Logger) slog.Handler {
+	return slogHandler{logger: l}
+}
+
+func (h slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.enabled(levelFromSlog(level))
+}
+
+func (h slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	logger := h.logger
+	if ctxLogger, ok := FromContext(ctx); ok {
+		logger = logger.WithTrace(ctxLogger.trace)
+	}
+	pairs := make([]LogPair, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		pairs = appendSlogAttr(pairs, h.prefix, attr)
+		return true
+	})
+	logger.logPairsWithCaller(r.PC, levelFromSlog(r.Level), r.Message, pairs)
+	return nil
+}
+
+func (h slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	pairs := make([]LogPair, 0, len(attrs))
+	for _, attr := range attrs {
+		pairs = appendSlogAttr(pairs, h.prefix, attr)
+	}
+	h.logger = h.logger.WithTraceLogs(pairs...)
+	return h
+}
+
+func (h slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h.prefix += name + "."
+	return h
+}
+
+func levelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return LevelDebug
+	case l < slog.LevelWarn:
+		return LevelInfo
+	case l < slog.LevelError:
+		return LevelWarn
+	case l < slog.LevelError+4:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+func appendSlogAttr(pairs []LogPair, prefix string, attr slog.Attr) []LogPair {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return pairs
+	}
+	key := prefix + attr.Key
+	v := attr.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = key + "."
+		}
+		for _, it := range v.Group() {
+			pairs = appendSlogAttr(pairs, groupPrefix, it)
+		}
+		return pairs
+	case slog.KindString:
+		return append(pairs, String(key, v.String()))
+	case slog.KindInt64:
+		return append(pairs, Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(pairs, Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(pairs, Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(pairs, Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(pairs, Duration(key, v.Duration()))
+	case slog.KindTime:
+		return append(pairs, Time(key, v.Time()))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(pairs, valuePair(key, pairKindError, err))
+		}
+		return append(pairs, Any(key, v.Any()))
+	}
+}
+
+// -------------------------------
+// Output writing into slog.Handler
+
+var (
+	_ Output      = (*slogOutput)(nil)
+	_ LevelSetter = (*slogOutput)(nil)
+	_ NamedOutput = (*slogOutput)(nil)
+)
+
+type slogOutput struct {
+	name    string
+	level   *LevelVar
+	handler slog.Handler
+}
+
+// MakeSlogOutput makes an Output writing records into handler, the pairs are written as attrs.
+func MakeSlogOutput(name string, level Level, handler slog.Handler) Output {
+	return slogOutput{name: name, level: NewLevelVar(level), handler: handler}
+}
+
+func (o slogOutput) Name() string { return o.name }
+
+func (o slogOutput) Level() Level { return o.level.Level() }
+
+func (o slogOutput) SetLevel(level Level) { o.level.Set(level) }
+
+func (o slogOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+// logWithCaller passes pc as the PC of slog.Record, e.g. for HandlerOptions.AddSource.
+func (o slogOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	ctx := context.Background()
+	level := levelToSlog(l)
+	if !o.handler.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, subject, pc)
+	for _, it := range pairs {
+		r.AddAttrs(it.slogAttr())
+	}
+	if o.name != "" {
+		r.AddAttrs(slog.String("logger", o.name))
+	}
+	o.handler.Handle(ctx, r)
+}
+
+func levelToSlog(l Level) slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+func (p LogPair) slogAttr() slog.Attr {
+	switch p.kind() {
+	case pairKindString, pairKindJSON:
+		return slog.String(p.key, p.str())
+	case pairKindInt64:
+		return slog.Int64(p.key, p.num)
+	case pairKindUint64:
+		return slog.Uint64(p.key, uint64(p.num))
+	case pairKindFloat64:
+		return slog.Float64(p.key, p.Value().(float64))
+	case pairKindBool:
+		return slog.Bool(p.key, p.num == 1)
+	case pairKindDuration:
+		return slog.Duration(p.key, time.Duration(p.num))
+	case pairKindTime:
+		return slog.Time(p.key, p.time())
+	case pairKindStringer:
+		return slog.String(p.key, stringOf(p.value))
+	case pairKindObject:
+		if v, ok := p.value.(ObjectMarshaler); ok {
+			enc := zapcore.NewMapObjectEncoder()
+			v.MarshalLogObject(enc)
+			return slog.Any(p.key, enc.Fields)
+		}
+	case pairKindArray:
+		if v, ok := p.value.(ArrayMarshaler); ok {
+			enc := zapcore.NewMapObjectEncoder()
+			enc.AddArray(p.key, v)
+			return slog.Any(p.key, enc.Fields[p.key])
+		}
+	}
+	return slog.Any(p.key, p.Value())
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	r := NewRecorder("", LevelInfo)
+	logger := slog.New(NewSlogHandler(NewLogger(r)))
+
+	logger.Debug("hidden")
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+
+	ctx := NewLogger().WithTraceLogs(String("trace_id", "T")).WrapContext(context.Background())
+	logger.With("service", "api").WithGroup("req").InfoContext(ctx, "handled",
+		"status", 200,
+		slog.Group("user", "id", uint64(7), "admin", false),
+		"dur", time.Second,
+		"err", errors.New("failed"),
+	)
+	logger.Error("failed")
+
+	assert.Equal(t, []string{"handled", "failed"}, r.Subjects())
+	record := r.FindBySubject("handled")[0]
+	assert.Equal(t, LevelInfo, record.Level)
+	assert.Equal(t, []string{"service", "trace_id", "req.status", "req.user.id", "req.user.admin", "req.dur", "req.err"}, record.Keys())
+	v, _ := record.Value("req.status")
+	assert.Equal(t, int64(200), v)
+	v, _ = record.Value("req.user.id")
+	assert.Equal(t, uint64(7), v)
+	v, _ = record.Value("req.dur")
+	assert.Equal(t, time.Second, v)
+	last, _ := r.Last()
+	assert.Equal(t, LevelError, last.Level)
+}
+
+func TestSlogOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := NewLogger(MakeSlogOutput("slog", LevelInfo, handler))
+	l.Debug("hidden")
+	assert.Equal(t, 0, buf.Len())
+
+	l.Warn("hello", Int("n", 1), Object("user", pairUser{name: "u", age: 3}), Stringer("d", time.Minute), Error(errors.New("e")))
+	var logged map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &logged))
+	assert.Equal(t, "WARN", logged["level"])
+	assert.Equal(t, "hello", logged["msg"])
+	assert.Equal(t, 1.0, logged["n"])
+	assert.Equal(t, map[string]interface{}{"name": "u", "age": 3.0}, logged["user"])
+	assert.Equal(t, "1m0s", logged["d"])
+	assert.Equal(t, "e", logged["error"])
+	assert.Equal(t, "slog", logged["logger"])
+}
+
+func TestSlogCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	slog.New(NewSlogHandler(NewLogger(MakeWriterOutput("", MakeLocalFormat(MessageFormatLogfmt), LevelInfo, buf)))).Info("handled")
+	assert.Regexp(t, regexp.MustCompile(`caller=log/slog_test\.go:\d+ msg=handled`), buf.String())
+
+	buf.Reset()
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true})
+	NewLogger(MakeSlogOutput("", LevelInfo, handler)).Info("written")
+	assert.Regexp(t, regexp.MustCompile(`"file":"\S+/log/slog_test\.go"`), buf.String())
+}
@@ -0,0 +1,284 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type SyslogFormat string
+
+const (
+	SyslogFormatRFC5424 SyslogFormat = "rfc5424"
+	SyslogFormatRFC3164 SyslogFormat = "rfc3164"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+type SyslogConfig struct {
+	// Network is one of unix, unixgram, udp and tcp,
+	// the local syslog daemon is used if both Network and Address are empty.
+	Network string `yaml:"network" json:"network"`
+	Address string `yaml:"address" json:"address"`
+	// Format is rfc5424(default) or rfc3164.
+	Format SyslogFormat `yaml:"format" json:"format"`
+	// Facility is the name like user(default), daemon or local0.
+	Facility string `yaml:"facility" json:"facility"`
+	// Tag is the APP-NAME of rfc5424 and the TAG of rfc3164, default is the name of the executable.
+	Tag      string `yaml:"tag" json:"tag"`
+	Hostname string `yaml:"hostname" json:"hostname"`
+}
+
+// MakeSyslogOutput makes an Output sending records to a syslog server,
+// the message of a record is encoded by fmt, and the severity is mapped from its level.
+func MakeSyslogOutput(name string, fmt LocalFormat, level Level, cfg SyslogConfig) (Output, error) {
+	framer, err := newSyslogFramer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	w, err := dialSyslog(cfg.Network, cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	framer.octetCounting = w.network == "tcp" && framer.format == SyslogFormatRFC5424
+	return makeFramedOutput(name, fmt, level, w, framer.frame), nil
+}
+
+type syslogFramer struct {
+	format        SyslogFormat
+	facility      int
+	tag           string
+	hostname      string
+	pid           int
+	octetCounting bool
+}
+
+func newSyslogFramer(cfg SyslogConfig) (*syslogFramer, error) {
+	f := &syslogFramer{format: cfg.Format, tag: cfg.Tag, hostname: cfg.Hostname, pid: os.Getpid()}
+	switch f.format {
+	case "":
+		f.format = SyslogFormatRFC5424
+	case SyslogFormatRFC5424, SyslogFormatRFC3164:
+	default:
+		return nil, fmt.Errorf("log: unknown syslog format %q", cfg.Format)
+	}
+	f.facility = syslogFacilities["user"]
+	if cfg.Facility != "" {
+		facility, ok := syslogFacilities[strings.ToLower(cfg.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("log: unknown syslog facility %q", cfg.Facility)
+		}
+		f.facility = facility
+	}
+	if f.tag == "" {
+		f.tag = filepath.Base(os.Args[0])
+	}
+	if f.hostname == "" {
+		f.hostname, _ = os.Hostname()
+	}
+	if f.hostname == "" {
+		f.hostname = "-"
+	}
+	return f, nil
+}
+
+func syslogSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func (f *syslogFramer) frame(ent zapcore.Entry, msg []byte) []byte {
+	pri := f.facility*8 + syslogSeverity(ent.Level)
+	var header string
+	if f.format == SyslogFormatRFC3164 {
+		header = fmt.Sprintf("<%d>%s %s %s[%d]: ", pri, ent.Time.Format(time.Stamp), f.hostname, f.tag, f.pid)
+	} else {
+		header = fmt.Sprintf("<%d>1 %s %s %s %d - - ", pri, ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"), f.hostname, f.tag, f.pid)
+	}
+	if f.octetCounting {
+		return []byte(strconv.Itoa(len(header)+len(msg)) + " " + header + string(msg))
+	}
+	return []byte(header + string(msg) + "\n")
+}
+
+var localSyslogAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+func dialSyslog(network, address string) (*connWriter, error) {
+	if network != "" || address != "" {
+		switch network {
+		case "unix", "unixgram", "udp", "tcp":
+		default:
+			return nil, fmt.Errorf("log: unknown syslog network %q", network)
+		}
+		return newConnWriter(network, address)
+	}
+	var errs []error
+	for _, address := range localSyslogAddresses {
+		for _, network := range []string{"unixgram", "unix"} {
+			w, err := newConnWriter(network, address)
+			if err == nil {
+				return w, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	return nil, fmt.Errorf("log: no local syslog daemon: %w", errors.Join(errs...))
+}
+
+// -------------------------------
+
+const (
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second
+	syslogMinBackoff   = time.Second
+	syslogMaxBackoff   = time.Minute
+)
+
+var errSyslogDown = errors.New("log: syslog server is unreachable, record dropped")
+
+// connWriter writes every packet by one Write call, and redials once if the connection is broken.
+// After a failed redial, records are dropped until the retry time passes, the delay doubles up to syslogMaxBackoff.
+type connWriter struct {
+	network string
+	address string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newConnWriter(network, address string) (*connWriter, error) {
+	w := &connWriter{network: network, address: address}
+	conn, err := net.DialTimeout(network, address, syslogDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *connWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		n, err := w.write(p)
+		if err == nil {
+			return n, nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		if n > 0 {
+			// part of p is sent, resending it on a new stream would duplicate that part
+			return n, err
+		}
+	}
+	now := time.Now()
+	if now.Before(w.retryAt) {
+		return 0, errSyslogDown
+	}
+	conn, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+	if err != nil {
+		w.backoff = min(max(2*w.backoff, syslogMinBackoff), syslogMaxBackoff)
+		w.retryAt = now.Add(w.backoff)
+		return 0, err
+	}
+	w.conn = conn
+	w.backoff = 0
+	w.retryAt = time.Time{}
+	return w.write(p)
+}
+
+func (w *connWriter) write(p []byte) (int, error) {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return 0, err
+	}
+	return w.conn.Write(p)
+}
+
+func (w *connWriter) Sync() error { return nil }
+
+func (w *connWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// -------------------------------
+
+// framedCore writes every encoded entry as a packet made by frame.
+type framedCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	writer zapcore.WriteSyncer
+	frame  func(ent zapcore.Entry, msg []byte) []byte
+}
+
+func makeFramedOutput(name string, fmt LocalFormat, level Level, w *connWriter, frame func(zapcore.Entry, []byte) []byte) Output {
+	output := newZapLoggerWithCore(name, fmt, level, func(enc zapcore.Encoder, enabler zapcore.LevelEnabler) zapcore.Core {
+		return &framedCore{LevelEnabler: enabler, enc: enc, writer: w, frame: frame}
+	})
+	output.closer = w
+	return output
+}
+
+func (c *framedCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, it := range fields {
+		it.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *framedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *framedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.Bytes()
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	_, err = c.writer.Write(c.frame(ent, msg))
+	buf.Free()
+	return err
+}
+
+func (c *framedCore) Sync() error {
+	return c.writer.Sync()
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func shortTempDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "log")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	return string(buf[:n])
+}
+
+func TestSyslogOutputPacket(t *testing.T) {
+	socket := filepath.Join(shortTempDir(t), "log.sock")
+	unixConn, err := net.ListenPacket("unixgram", socket)
+	assert.Nil(t, err)
+	defer unixConn.Close()
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer udpConn.Close()
+
+	cases := []struct {
+		cfg     SyslogConfig
+		conn    net.PacketConn
+		pattern string
+	}{
+		{
+			SyslogConfig{Network: "unixgram", Address: socket, Tag: "app", Hostname: "host", Facility: "local0"},
+			unixConn,
+			`^<131>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ host app \d+ - - \{"level":"error",.*"msg":"failed","n":1\}\n$`,
+		},
+		{
+			SyslogConfig{Network: "udp", Address: udpConn.LocalAddr().String(), Format: SyslogFormatRFC3164, Tag: "app", Hostname: "host"},
+			udpConn,
+			`^<11>\w{3} [ \d]\d \d\d:\d\d:\d\d host app\[\d+\]: \{"level":"error",.*"msg":"failed","n":1\}\n$`,
+		},
+	}
+	for _, it := range cases {
+		output, err := MakeSyslogOutput("", MakeLocalFormat(MessageFormatJSON), LevelInfo, it.cfg)
+		assert.Nil(t, err)
+		l := NewLogger(output)
+		l.Debug("hidden")
+		l.Error("failed", Int("n", 1))
+		assert.Regexp(t, regexp.MustCompile(it.pattern), readPacket(t, it.conn))
+		assert.Nil(t, l.Close())
+	}
+}
+
+func TestSyslogOutputTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	output, err := MakeSyslogOutput("", MakeLocalFormat(MessageFormatText), LevelDebug, SyslogConfig{Network: "tcp", Address: listener.Addr().String()})
+	assert.Nil(t, err)
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	l := NewLogger(output)
+	l.Warn("first")
+	l.Info("second")
+	reader := bufio.NewReader(conn)
+	for _, subject := range []string{"first", "second"} {
+		size, err := reader.ReadString(' ')
+		assert.Nil(t, err)
+		n := 0
+		for _, c := range strings.TrimSpace(size) {
+			n = n*10 + int(c-'0')
+		}
+		frame := make([]byte, n)
+		_, err = io.ReadFull(reader, frame)
+		assert.Nil(t, err)
+		assert.Contains(t, string(frame), subject)
+	}
+	assert.Nil(t, l.Close())
+}
+
+func TestConnWriterBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	w, err := newConnWriter("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	conn.Close()
+	listener.Close()
+
+	// the broken connection fails at some write, then the redial fails
+	var errs []error
+	for i := 0; i < 100 && len(errs) == 0; i++ {
+		if _, err := w.Write([]byte("line\n")); err != nil {
+			errs = append(errs, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.NotEmpty(t, errs)
+	assert.Nil(t, w.conn)
+	assert.Equal(t, syslogMinBackoff, w.backoff)
+
+	start := time.Now()
+	n, err := w.Write([]byte("line\n"))
+	assert.Equal(t, 0, n)
+	assert.ErrorIs(t, err, errSyslogDown)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Nil(t, w.Close())
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	for _, cfg := range []SyslogConfig{
+		{Network: "ipx", Address: "a"},
+		{Format: "rfc9999"},
+		{Facility: "local9"},
+	} {
+		_, err := MakeSyslogOutput("", MakeLocalFormat(MessageFormatJSON), LevelInfo, cfg)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestJournaldOutput(t *testing.T) {
+	socket := filepath.Join(shortTempDir(t), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socket)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	output, err := MakeJournaldOutput("", MakeLocalFormat(MessageFormatText), LevelInfo, JournaldConfig{Socket: socket, Identifier: "app"})
+	assert.Nil(t, err)
+	l := NewLogger(output)
+	l.Warn("hello")
+	packet := readPacket(t, conn)
+	assert.Contains(t, packet, "PRIORITY=4\nSYSLOG_IDENTIFIER=app\n")
+	assert.Contains(t, packet, "CODE_FILE=")
+	assert.Regexp(t, regexp.MustCompile("\nMESSAGE=.*\twarn\t.*hello\n$"), packet)
+
+	l.Error("multi\nline")
+	packet = readPacket(t, conn)
+	i := strings.Index(packet, "\nMESSAGE\n")
+	assert.True(t, i > 0)
+	body := []byte(packet[i+len("\nMESSAGE\n"):])
+	size := binary.LittleEndian.Uint64(body[:8])
+	assert.Equal(t, int(size)+9, len(body))
+	assert.True(t, bytes.Contains(body, []byte("multi\nline")))
+	assert.Nil(t, l.Close())
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	_ Output       = (*teeOutput)(nil)
+	_ LevelSetter  = (*teeOutput)(nil)
+	_ NamedOutput  = (*teeOutput)(nil)
+	_ callerOutput = (*teeOutput)(nil)
+)
+
+type teeOutput struct {
+	name    string
+	outputs []Output
+}
+
+// NewTeeOutput makes an Output writing every record into outputs, each with its own level,
+// so a group of outputs can be used as one, e.g. by NewRoutedOutput.
+// Sync and Close are applied to all outputs, and their errors are joined.
+// To write the same encoded records into several writers, use MakeWriterOutput with io.MultiWriter.
+func NewTeeOutput(name string, outputs ...Output) Output {
+	return teeOutput{name: name, outputs: outputs}
+}
+
+func (o teeOutput) Name() string { return o.name }
+
+// Level is the lowest level of outputs.
+func (o teeOutput) Level() Level {
+	level := LevelFatal
+	for _, it := range o.outputs {
+		if l := it.Level(); l < level {
+			level = l
+		}
+	}
+	return level
+}
+
+// SetLevel sets the level of all outputs implementing LevelSetter.
+func (o teeOutput) SetLevel(level Level) {
+	for _, it := range o.outputs {
+		if setter, ok := it.(LevelSetter); ok {
+			setter.SetLevel(level)
+		}
+	}
+}
+
+func (o teeOutput) canSetLevel() bool {
+	for _, it := range o.outputs {
+		if canSetLevel(it) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o teeOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o teeOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	for _, it := range o.outputs {
+		if l >= it.Level() {
+			logToOutput(it, pc, l, subject, pairs)
+		}
+	}
+}
+
+func (o teeOutput) Sync() error {
+	var errs []error
+	for _, it := range o.outputs {
+		if syncer, ok := it.(Syncer); ok {
+			if err := syncer.Sync(); err != nil {
+				errs = append(errs, outputError(it, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o teeOutput) Close() error {
+	var errs []error
+	for _, it := range o.outputs {
+		if closer, ok := it.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, outputError(it, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syncErrorWriter struct {
+	bytes.Buffer
+}
+
+func (w *syncErrorWriter) Sync() error { return errors.New("sync failed") }
+
+func TestWriterOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(MakeWriterOutput("buf", MakeLocalFormat(MessageFormatLogfmt), LevelInfo, buf))
+	l.Debug("hidden")
+	l.Info("hello", String("k", "v"))
+	assert.Regexp(t, regexp.MustCompile(`^ts=\S+ level=info logger=buf caller=log/tee_test\.go:\d+ msg=hello k=v\n$`), buf.String())
+	assert.Nil(t, l.Close())
+
+	w := &syncErrorWriter{}
+	l = NewLogger(MakeWriterOutput("w", MakeLocalFormat(MessageFormatJSON), LevelInfo, w))
+	assert.EqualError(t, l.Sync(), `log: output "w": sync failed`)
+}
+
+func TestTeeOutput(t *testing.T) {
+	info := &bytes.Buffer{}
+	warn := &bytes.Buffer{}
+	errRecorder := NewRecorder("errors", LevelError)
+	tee := NewTeeOutput("tee",
+		MakeWriterOutput("info", MakeLocalFormat(MessageFormatLogfmt), LevelInfo, info),
+		MakeWriterOutput("warn", MakeLocalFormat(MessageFormatLogfmt), LevelWarn, warn),
+		errRecorder,
+	)
+	assert.Equal(t, LevelInfo, tee.Level())
+	// the caller is kept through the wrapping outputs
+	l := NewLogger(NewRoutedOutput(tee, RouteNot(RouteSubjectPrefix("skip"))))
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	l.Error("skip")
+
+	assert.Equal(t, 3, strings.Count(info.String(), "\n"))
+	assert.Equal(t, 2, strings.Count(warn.String(), "\n"))
+	assert.Equal(t, []string{"error"}, errRecorder.Subjects())
+	assert.Regexp(t, regexp.MustCompile(`caller=log/tee_test\.go:\d+ msg=info`), info.String())
+
+	level, ok := l.OutputLevel("tee")
+	assert.True(t, ok)
+	assert.Equal(t, LevelInfo, level)
+	assert.Nil(t, l.SetOutputLevel("tee", LevelError))
+	assert.Equal(t, LevelError, tee.Level())
+	assert.Equal(t, LevelError, errRecorder.Level())
+	assert.Nil(t, l.Close())
+
+	tee = NewTeeOutput("bad", MakeWriterOutput("w", MakeLocalFormat(MessageFormatJSON), LevelInfo, &syncErrorWriter{}))
+	assert.EqualError(t, NewLogger(tee).Sync(), `log: output "bad": log: output "w": sync failed`)
+}
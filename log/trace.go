@@ -1,15 +1,29 @@
 package log
 
 import (
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	fieldSpanID       = "span_id"
+	fieldParentSpanID = "parent_span_id"
+
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+)
+
 type Trace struct {
 	startTime time.Time
 	pairs     []LogPair
+	span      SpanContext
 }
 
 func (t Trace) length() int { return len(t.pairs) }
@@ -22,21 +36,67 @@ func (t Trace) merge(other Trace) Trace {
 	if t.startTime.IsZero() {
 		t.startTime = other.startTime
 	}
+	if !t.span.TraceID.IsValid() {
+		t.span = other.span
+	}
 	if t.pairs == nil {
 		t.pairs = other.pairs
 	} else {
-		t.pairs = append(t.pairs, other.pairs...)
+		// 限制容量，避免与其它logger共用底层数组
+		t.pairs = append(t.pairs[:len(t.pairs):len(t.pairs)], other.pairs...)
 	}
 	return t
 }
 
+// SpanContext returns the W3C trace context of t, it is invalid if t is not created with one.
+func (t Trace) SpanContext() SpanContext {
+	return t.span
+}
+
+// NewTrace creates a Trace logging traceID,
+// the traceID in the format of TraceID is used as the trace-id of SpanContext too.
 func NewTrace(traceID string, t time.Time, pairs ...LogPair) Trace {
+	trace := Trace{startTime: t}
 	if traceID != "" {
-		pairs = append(pairs, Any(fieldTraceID, traceID))
+		pairs = append(pairs, String(fieldTraceID, traceID))
+		if id, err := ParseTraceID(traceID); err == nil {
+			trace.span.TraceID = id
+		}
 	}
-	return Trace{startTime: t, pairs: pairs}
+	trace.pairs = pairs
+	return trace
+}
+
+// NewTraceWithSpanContext creates a Trace logging trace_id and span_id of sc.
+func NewTraceWithSpanContext(sc SpanContext, t time.Time, pairs ...LogPair) Trace {
+	pairs = append(pairs, String(fieldTraceID, sc.TraceID.String()), String(fieldSpanID, sc.SpanID.String()))
+	return Trace{startTime: t, pairs: pairs, span: sc}
 }
 
+// StartSpan creates a child Trace with the same trace-id and a new span-id,
+// the span-id of t is logged as parent_span_id, and the duration is counted from startTime.
+func (t Trace) StartSpan(startTime time.Time, pairs ...LogPair) Trace {
+	child := SpanContext{TraceID: t.span.TraceID, SpanID: NewSpanID(), Flags: t.span.Flags, State: t.span.State}
+	if !child.TraceID.IsValid() {
+		child.TraceID = NewTraceID()
+	}
+	inherited := make([]LogPair, 0, len(t.pairs)+len(pairs)+3)
+	for _, it := range t.pairs {
+		switch it.key {
+		case fieldTraceID, fieldSpanID, fieldParentSpanID:
+		default:
+			inherited = append(inherited, it)
+		}
+	}
+	inherited = append(inherited, pairs...)
+	if t.span.SpanID.IsValid() {
+		inherited = append(inherited, String(fieldParentSpanID, t.span.SpanID.String()))
+	}
+	return NewTraceWithSpanContext(child, startTime, inherited...)
+}
+
+// -------------------------------
+
 type TraceID struct {
 	v [16]byte
 }
@@ -46,6 +106,154 @@ func NewTraceID() TraceID {
 	return t
 }
 
+// ParseTraceID parses 32 lowercase hex characters.
+func ParseTraceID(s string) (TraceID, error) {
+	var t TraceID
+	if err := decodeHexID(t.v[:], s); err != nil {
+		return TraceID{}, fmt.Errorf("log: invalid trace-id %q: %w", s, err)
+	}
+	return t, nil
+}
+
 func (t TraceID) String() string {
 	return hex.EncodeToString(t.v[:])
 }
+
+// IsValid reports whether t is not all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) Bytes() [16]byte { return t.v }
+
+type SpanID struct {
+	v [8]byte
+}
+
+func NewSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		rand.Read(s.v[:])
+	}
+	return s
+}
+
+// ParseSpanID parses 16 lowercase hex characters.
+func ParseSpanID(s string) (SpanID, error) {
+	var id SpanID
+	if err := decodeHexID(id.v[:], s); err != nil {
+		return SpanID{}, fmt.Errorf("log: invalid span-id %q: %w", s, err)
+	}
+	return id, nil
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s.v[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) Bytes() [8]byte { return s.v }
+
+func decodeHexID(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return errors.New("wrong length")
+	}
+	if strings.ToLower(s) != s {
+		return errors.New("not lowercase")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// -------------------------------
+
+type TraceFlags byte
+
+const TraceFlagsSampled TraceFlags = 0x01
+
+func (f TraceFlags) IsSampled() bool { return f&TraceFlagsSampled != 0 }
+
+// SpanContext is the W3C Trace Context, see https://www.w3.org/TR/trace-context/
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   TraceFlags
+	// State is the raw value of tracestate header.
+	State string
+}
+
+// NewSpanContext starts a new sampled trace.
+func NewSpanContext() SpanContext {
+	return SpanContext{TraceID: NewTraceID(), SpanID: NewSpanID(), Flags: TraceFlagsSampled}
+}
+
+// SpanContextFromIDs adapts the span context of other tracing libraries, e.g. for OpenTelemetry:
+//
+//	sc := trace.SpanContextFromContext(ctx)
+//	log.SpanContextFromIDs(sc.TraceID(), sc.SpanID(), byte(sc.TraceFlags()), sc.TraceState().String())
+func SpanContextFromIDs(traceID [16]byte, spanID [8]byte, flags byte, state string) SpanContext {
+	return SpanContext{TraceID: TraceID{v: traceID}, SpanID: SpanID{v: spanID}, Flags: TraceFlags(flags), State: state}
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// ParseTraceParent parses the traceparent header, and the tracestate header if it is not empty.
+func ParseTraceParent(traceparent, tracestate string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 || version[0] == 0xff || strings.ToLower(parts[0]) != parts[0] {
+		return SpanContext{}, fmt.Errorf("log: invalid traceparent version %q", parts[0])
+	}
+	// 未来的版本可以在后面追加字段
+	if version[0] == 0 && len(parts) != 4 {
+		return SpanContext{}, fmt.Errorf("log: invalid traceparent %q", traceparent)
+	}
+	var sc SpanContext
+	if sc.TraceID, err = ParseTraceID(parts[1]); err != nil {
+		return SpanContext{}, err
+	}
+	if sc.SpanID, err = ParseSpanID(parts[2]); err != nil {
+		return SpanContext{}, err
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 || strings.ToLower(parts[3]) != parts[3] {
+		return SpanContext{}, fmt.Errorf("log: invalid traceparent flags %q", parts[3])
+	}
+	sc.Flags = TraceFlags(flags[0])
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("log: invalid traceparent %q with zero id", traceparent)
+	}
+	sc.State = strings.TrimSpace(tracestate)
+	return sc, nil
+}
+
+// TraceParent formats sc as the value of traceparent header of version 00.
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, byte(sc.Flags))
+}
+
+// SpanContextFromHeader extracts the SpanContext from traceparent and tracestate headers.
+func SpanContextFromHeader(h http.Header) (SpanContext, error) {
+	return ParseTraceParent(h.Get(HeaderTraceParent), strings.Join(h.Values(HeaderTraceState), ","))
+}
+
+// InjectHeader sets traceparent and tracestate headers of h, e.g. for an outgoing request.
+func (sc SpanContext) InjectHeader(h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(HeaderTraceParent, sc.TraceParent())
+	if sc.State != "" {
+		h.Set(HeaderTraceState, sc.State)
+	} else {
+		h.Del(HeaderTraceState)
+	}
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	raw := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(raw, "congo=t61rcWkgMzE")
+	assert.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Flags.IsSampled())
+	assert.Equal(t, raw, sc.TraceParent())
+
+	h := http.Header{}
+	sc.InjectHeader(h)
+	assert.Equal(t, raw, h.Get(HeaderTraceParent))
+	assert.Equal(t, "congo=t61rcWkgMzE", h.Get(HeaderTraceState))
+	parsed, err := SpanContextFromHeader(h)
+	assert.Nil(t, err)
+	assert.Equal(t, sc, parsed)
+
+	_, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", "")
+	assert.Nil(t, err)
+
+	for _, it := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(it, "")
+		assert.NotNil(t, err, it)
+	}
+}
+
+func TestLoggerStartSpan(t *testing.T) {
+	r := NewRecorder("", LevelDebug)
+	root := SpanContextFromIDs([16]byte{1}, [8]byte{2}, 1, "")
+	l := NewLogger(r).WithTrace(NewTraceWithSpanContext(root, time.Now(), String("user", "u")))
+	l.Info("root")
+	record, _ := r.Last()
+	assert.Equal(t, []string{"user", "trace_id", "span_id", "trace_dur"}, record.Keys())
+
+	child := l.StartSpan(time.Now(), String("op", "query"))
+	sc := child.SpanContext()
+	assert.Equal(t, root.TraceID, sc.TraceID)
+	assert.NotEqual(t, root.SpanID, sc.SpanID)
+	assert.True(t, sc.SpanID.IsValid())
+
+	child.Info("child")
+	record, _ = r.Last()
+	assert.Equal(t, []string{"user", "op", "parent_span_id", "trace_id", "span_id", "trace_dur"}, record.Keys())
+	v, _ := record.Value("parent_span_id")
+	assert.Equal(t, root.SpanID.String(), v)
+	v, _ = record.Value("span_id")
+	assert.Equal(t, sc.SpanID.String(), v)
+
+	// the root logger is not changed by the child
+	l.Info("root")
+	record, _ = r.Last()
+	assert.False(t, record.Has("parent_span_id"))
+
+	traced := NewLoggerWithTrace(&Logger{}, time.Now())
+	assert.True(t, traced.SpanContext().TraceID.IsValid())
+	assert.Equal(t, traced.SpanContext().TraceID, traced.StartSpan(time.Now()).SpanContext().TraceID)
+}
+
+func TestTraceMergeDoesNotShareArray(t *testing.T) {
+	base := NewLogger().WithTraceLogs(make([]LogPair, 1, 4)...)
+	a := base.WithTraceLogs(String("a", "1"))
+	b := base.WithTraceLogs(String("b", "2"))
+	assert.Equal(t, "a", a.trace.pairs[1].key)
+	assert.Equal(t, "b", b.trace.pairs[1].key)
+}
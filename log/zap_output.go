@@ -1,8 +1,14 @@
 package log
 
 import (
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,17 +18,47 @@ import (
 
 const callerSkip = 3
 
-var _ Output = (*zapOutput)(nil)
+var (
+	_ Output       = (*zapOutput)(nil)
+	_ LevelSetter  = (*zapOutput)(nil)
+	_ NamedOutput  = (*zapOutput)(nil)
+	_ io.Closer    = (*zapOutput)(nil)
+	_ Syncer       = (*zapOutput)(nil)
+	_ callerOutput = (*zapOutput)(nil)
+)
 
 type zapOutput struct {
 	formatKeys map[string]bool
 
-	level  Level
+	name   string
+	level  *LevelVar
+	logger *zap.Logger
 	output *zap.SugaredLogger
+	closer io.Closer
+}
+
+func (o zapOutput) Sync() error {
+	return o.output.Sync()
+}
+
+// Close stops the rotation of file output and closes the file.
+func (o zapOutput) Close() error {
+	if o.closer == nil {
+		return nil
+	}
+	return o.closer.Close()
 }
 
 func (o zapOutput) Level() Level {
-	return o.level
+	return o.level.Level()
+}
+
+func (o zapOutput) SetLevel(level Level) {
+	o.level.Set(level)
+}
+
+func (o zapOutput) Name() string {
+	return o.name
 }
 
 func (o *zapOutput) Log(l Level, msg string, argPairs []interface{}) {
@@ -41,26 +77,81 @@ func (o *zapOutput) Log(l Level, msg string, argPairs []interface{}) {
 }
 
 func (o zapOutput) LogModuleAndPairs(l Level, subject string, pairs []LogPair) {
-	argPairs := make([]interface{}, 0, len(pairs)*2)
+	o.logWithCaller(callerPC(1), l, subject, pairs)
+}
+
+func (o zapOutput) logWithCaller(pc uintptr, l Level, subject string, pairs []LogPair) {
+	ce := o.logger.Check(makeZapLevel(l), subject)
+	if ce == nil {
+		return
+	}
+	if pc != 0 {
+		ce.Caller = entryCaller(pc)
+	}
+	fields := getZapFields()
 	for i := range pairs {
 		key := pairs[i].key
 		if o.formatKeys[key] {
 			key = "_" + key
 		}
-		argPairs = append(argPairs, key, pairs[i].value)
+		*fields = append(*fields, pairs[i].zapField(key))
 	}
-	switch l {
-	case LevelDebug:
-		o.output.Debugw(subject, argPairs...)
-	case LevelInfo:
-		o.output.Infow(subject, argPairs...)
-	case LevelWarn:
-		o.output.Warnw(subject, argPairs...)
-	case LevelError:
-		o.output.Errorw(subject, argPairs...)
-	case LevelFatal:
-		o.output.Fatalw(subject, argPairs...)
+	ce.Write(*fields...)
+	putZapFields(fields)
+}
+
+// callerCache keeps the resolved callers by program counter,
+// runtime.CallersFrames allocates on every call while the call sites of a program are limited.
+var callerCache = struct {
+	sync.RWMutex
+	callers map[uintptr]zapcore.EntryCaller
+}{callers: map[uintptr]zapcore.EntryCaller{}}
+
+func entryCaller(pc uintptr) zapcore.EntryCaller {
+	callerCache.RLock()
+	caller, ok := callerCache.callers[pc]
+	callerCache.RUnlock()
+	if ok {
+		return caller
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	caller = zapcore.EntryCaller{Defined: true, PC: frame.PC, File: frame.File, Line: frame.Line, Function: frame.Function}
+	callerCache.Lock()
+	callerCache.callers[pc] = caller
+	callerCache.Unlock()
+	return caller
+}
+
+// logPackageDir is the directory of this package, used to skip its frames in the stacks.
+var logPackageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// isInternalFrame reports the frames of this package, slog and runtime, e.g. runtime.gopanic of recovered panics.
+func isInternalFrame(file, function string) bool {
+	if filepath.Dir(file) == logPackageDir {
+		return !strings.HasSuffix(file, "_test.go")
+	}
+	return strings.HasPrefix(function, "log/slog.") || strings.HasPrefix(function, "runtime.")
+}
+
+var zapFieldsPool = sync.Pool{New: func() any {
+	fields := make([]zap.Field, 0, 16)
+	return &fields
+}}
+
+func getZapFields() *[]zap.Field {
+	return zapFieldsPool.Get().(*[]zap.Field)
+}
+
+func putZapFields(fields *[]zap.Field) {
+	if cap(*fields) > 256 {
+		return
 	}
+	clear(*fields)
+	*fields = (*fields)[:0]
+	zapFieldsPool.Put(fields)
 }
 
 func (o *zapOutput) LogPlainMessage(l Level, args []interface{}) {
@@ -93,28 +184,82 @@ func (o *zapOutput) LogFormatted(l Level, format string, args []interface{}) {
 	}
 }
 
+// continueAfterFatal lets Logger.Fatal decide when to exit,
+// so the other outputs still get the log and can be flushed.
+type continueAfterFatal struct{}
+
+func (continueAfterFatal) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {}
+
 func newZapConsoleWriter(stream zapcore.WriteSyncer) zapcore.WriteSyncer {
-	return zapcore.Lock(stream)
+	return zapcore.Lock(consoleSyncer{stream})
+}
+
+// consoleSyncer ignores the errors of syncing a terminal or pipe, which can not be synced.
+type consoleSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (s consoleSyncer) Sync() error {
+	err := s.WriteSyncer.Sync()
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+	return err
+}
+
+type zapFileWriter struct {
+	*lumberjack.Logger
+	scheduler *rotationScheduler
+
+	mu       sync.RWMutex
+	closed   bool
+	closeErr error
+}
+
+// Write refuses to reopen the file after closed.
+func (w *zapFileWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	return w.Logger.Write(p)
+}
+
+func (w *zapFileWriter) Sync() error { return nil }
+
+func (w *zapFileWriter) Close() error {
+	if w.scheduler != nil {
+		w.scheduler.Stop()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		w.closeErr = w.Logger.Close()
+	}
+	return w.closeErr
+}
+
+func newZapFileWriter(location string, rotation FileRotation) *zapFileWriter {
+	return newZapFileWriterWithClock(location, rotation, systemClock{})
 }
 
-func newZapFileWriter(location string, rotation FileRotation) zapcore.WriteSyncer {
-	fileLogger := lumberjack.Logger{
+func newZapFileWriterWithClock(location string, rotation FileRotation, c clock) *zapFileWriter {
+	fileLogger := &lumberjack.Logger{
 		Filename:   location,
 		MaxSize:    rotation.MaxSize,
 		Compress:   rotation.Compress,
 		MaxAge:     rotation.MaxAge,
 		MaxBackups: rotation.MaxBackups,
+		LocalTime:  rotation.LocalTime,
 	}
+	w := &zapFileWriter{Logger: fileLogger}
 	if rotation.RotateOnTime {
-		go func() {
-			interval := parseRotationPeriod(rotation.RotatePeriod, rotation.RotateAfter)
-			for {
-				<-time.After(interval)
-				fileLogger.Rotate()
-			}
-		}()
+		w.scheduler = newRotationScheduler(c, location, rotation, fileLogger.Rotate)
+		w.scheduler.start()
 	}
-	return zapcore.AddSync(&fileLogger)
+	return w
 }
 
 func newZapWriter(w io.Writer) zapcore.WriteSyncer {
@@ -122,16 +267,26 @@ func newZapWriter(w io.Writer) zapcore.WriteSyncer {
 }
 
 func newZapLogger(name string, fmt LocalFormat, level Level, writer zapcore.WriteSyncer) zapOutput {
-	encoder := makeZapEncoder(fmt.Format.isJSON(), makeZapEncoderConfig(fmt))
-	core := zapcore.NewCore(encoder, writer, makeZapLevel(level))
-	logger := zap.New(core,
-		zap.AddCallerSkip(callerSkip),
-		zap.AddCaller(),
-	).Sugar()
+	return newZapLoggerWithCore(name, fmt, level, func(encoder zapcore.Encoder, enabler zapcore.LevelEnabler) zapcore.Core {
+		return zapcore.NewCore(encoder, writer, enabler)
+	})
+}
+
+// newZapLoggerWithCore lets the outputs framing every record, e.g. syslog, encode by fmt with their own core.
+func newZapLoggerWithCore(name string, fmt LocalFormat, level Level, makeCore func(zapcore.Encoder, zapcore.LevelEnabler) zapcore.Core) zapOutput {
+	encoder := makeZapEncoder(fmt)
+	levelVar := NewLevelVar(level)
+	enabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= makeZapLevel(levelVar.Level())
+	})
+	core := makeCore(encoder, enabler)
+	// the caller of LogModuleAndPairs is set by logWithCaller
+	logger := zap.New(core, zap.WithFatalHook(continueAfterFatal{}))
 	if name != "" {
 		logger = logger.Named(name)
 	}
-	return zapOutput{level: level, output: logger, formatKeys: map[string]bool{
+	sugar := logger.WithOptions(zap.AddCallerSkip(callerSkip), zap.AddCaller()).Sugar()
+	return zapOutput{name: name, level: levelVar, logger: logger, output: sugar, formatKeys: map[string]bool{
 		fmt.CallerKey:  true,
 		fmt.LevelKey:   true,
 		fmt.MessageKey: true,
@@ -155,11 +310,20 @@ func makeZapEncoderConfig(f LocalFormat) zapcore.EncoderConfig {
 	return cfg
 }
 
-func makeZapEncoder(isJSON bool, encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
-	if isJSON {
+func makeZapEncoder(f LocalFormat) zapcore.Encoder {
+	encoderConfig := makeZapEncoderConfig(f)
+	switch f.Format {
+	case MessageFormatJSON:
 		return zapcore.NewJSONEncoder(encoderConfig)
+	case MessageFormatDev:
+		return newDevEncoder(encoderConfig, false)
+	case MessageFormatLogfmt:
+		return newLogfmtEncoder(encoderConfig)
+	case MessageFormatCEF:
+		return newCEFEncoder(encoderConfig, f.CEF)
+	default:
+		return zapcore.NewConsoleEncoder(encoderConfig)
 	}
-	return zapcore.NewConsoleEncoder(encoderConfig)
 }
 
 func makeZapLevel(l Level) zapcore.Level {
@@ -179,17 +343,17 @@ func makeZapLevel(l Level) zapcore.Level {
 	}
 }
 
-func parseRotationPeriod(period string, n int) time.Duration {
+func rotationPeriodUnit(period string) (time.Duration, bool) {
 	switch strings.ToLower(period) {
 	case "day", "daily", "d":
-		return time.Hour * 24 * time.Duration(n)
+		return time.Hour * 24, true
 	case "hour", "hourly", "h":
-		return time.Hour * time.Duration(n)
+		return time.Hour, true
 	case "minute", "m":
-		return time.Minute * time.Duration(n)
+		return time.Minute, true
 	case "second", "s":
-		return time.Second * time.Duration(n)
+		return time.Second, true
 	default:
-		return time.Hour * 24
+		return 0, false
 	}
 }